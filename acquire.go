@@ -0,0 +1,80 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AcquireContext takes the lock like LockContext and returns a function releasing it.
+// The release function is safe to call more than once; only the first call unlocks.
+func (m *Mutex) AcquireContext(ctx context.Context) (func() error, error) {
+	if err := m.LockContext(ctx); err != nil {
+		return nil, err
+	}
+	return m.releaseFunc(), nil
+}
+
+// TryAcquire attempts the lock like TryLockContext. On success it returns a function
+// releasing the lock; on contention it returns false and a release function that does
+// nothing, so that the result can always be deferred.
+func (m *Mutex) TryAcquire(ctx context.Context) (bool, func() error, error) {
+	acquired, err := m.TryLockContext(ctx)
+	if err != nil || !acquired {
+		return false, noRelease, err
+	}
+	return true, m.releaseFunc(), nil
+}
+
+// LockPreferImmediate makes one non-blocking attempt like TryLockContext and, only if
+// the lock is taken, waits for it with a blocking lock whose lock_timeout is maxWait, so
+// that PostgreSQL queues the waiter instead of it polling. Returns ErrLockTimeout if the
+// lock is not acquired within maxWait.
+func (m *Mutex) LockPreferImmediate(ctx context.Context, maxWait time.Duration) error {
+	if maxWait <= 0 {
+		return fmt.Errorf("max wait must be positive")
+	}
+	acquired, err := m.TryLockContext(ctx)
+	if err != nil || acquired {
+		return err
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	return m.LockContext(wctx)
+}
+
+// LockChan takes the lock like LockContext in a new goroutine and sends the result on the
+// returned channel, so acquisition can be combined with other events in a select. A nil
+// result means the lock is now held by m. If ctx is done by the time the lock is taken,
+// it is released again and ctx.Err() is sent. The channel is buffered, so the goroutine
+// never blocks on an abandoned channel; a nil result that was never received still
+// leaves the lock held.
+func (m *Mutex) LockChan(ctx context.Context) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		err := m.LockContext(ctx)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+			_ = m.UnlockContext(m.context())
+		}
+		ch <- err
+	}()
+	return ch
+}
+
+// releaseFunc returns a function that unlocks m on its first call only.
+func (m *Mutex) releaseFunc() func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() { err = m.Unlock() })
+		return err
+	}
+}
+
+// noRelease is the release function of an attempt that did not acquire the lock.
+func noRelease() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireContextReleasesOnce(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(127))
+
+	release, err := m.AcquireContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := release(); err != nil {
+			t.Fatalf("release %d = %v", i, err)
+		}
+	}
+	if n := s.count("pg_advisory_unlock("); n != 1 {
+		t.Errorf("%d unlock statements, want 1", n)
+	}
+}
+
+func TestTryAcquire(t *testing.T) {
+	db := newFakeDB()
+	a := newTestMutex(t, WithConn(db.session()), WithResourceID(127))
+	b := newTestMutex(t, WithConn(db.session()), WithResourceID(127))
+	ctx := context.Background()
+
+	ok, release, err := a.TryAcquire(ctx)
+	if err != nil || !ok {
+		t.Fatalf("TryAcquire = %t, %v", ok, err)
+	}
+	ok, noop, err := b.TryAcquire(ctx)
+	if err != nil || ok {
+		t.Fatalf("contended TryAcquire = %t, %v, want false", ok, err)
+	}
+	// The release of a failed attempt does nothing, in particular it does not release a's lock
+	if err := noop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("Unlock after release = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestLockPreferImmediate(t *testing.T) {
+	db := newFakeDB()
+	holder := db.session()
+	if _, err := holder.Exec(context.Background(), sqlLock, int64(194)); err != nil {
+		t.Fatal(err)
+	}
+	s := db.session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(194))
+
+	if err := m.LockPreferImmediate(context.Background(), 20*time.Millisecond); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("LockPreferImmediate on a held lock = %v, want ErrLockTimeout", err)
+	}
+	if s.count("pg_try_advisory_lock(") != 1 || s.count("pg_advisory_lock(") != 1 {
+		t.Errorf("statements %q, want one attempt and one blocking lock", s.statements())
+	}
+
+	time.AfterFunc(20*time.Millisecond, func() { _, _ = holder.Exec(context.Background(), sqlUnlock, int64(194)) })
+	if err := m.LockPreferImmediate(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("LockPreferImmediate released while waiting = %v", err)
+	}
+	if err := m.LockPreferImmediate(context.Background(), 0); err == nil {
+		t.Error("zero max wait accepted")
+	}
+}
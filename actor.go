@@ -0,0 +1,89 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// errActorStopped is returned for statements issued after the connection actor was stopped.
+var errActorStopped = errors.New("connection actor stopped")
+
+// actorConn funnels all statements for a connection through a single goroutine that
+// owns it, so concurrent callers never use the underlying connection at the same time.
+type actorConn struct {
+	conn Conn
+	reqs chan func()
+	done chan struct{}
+	once sync.Once
+}
+
+func newActorConn(c Conn) *actorConn {
+	a := &actorConn{conn: c, reqs: make(chan func()), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *actorConn) run() {
+	for {
+		select {
+		case fn := <-a.reqs:
+			fn()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// do runs fn on the actor goroutine and waits for it to finish. It gives up with
+// ctx.Err() if ctx is done before the actor picks fn up.
+func (a *actorConn) do(ctx context.Context, fn func()) error {
+	finished := make(chan struct{})
+	select {
+	case a.reqs <- func() { fn(); close(finished) }:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-a.done:
+		return errActorStopped
+	}
+	<-finished
+	return nil
+}
+
+func (a *actorConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	var err error
+	if derr := a.do(ctx, func() { tag, err = a.conn.Exec(ctx, sql, arguments...) }); derr != nil {
+		return pgconn.CommandTag{}, derr
+	}
+	return tag, err
+}
+
+// QueryRow defers the query to Scan, as pgx rows read from the connection while scanning.
+func (a *actorConn) QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row {
+	return &actorRow{a: a, ctx: ctx, sql: sql, args: optionsAndArgs}
+}
+
+// stop terminates the actor goroutine. Statements issued afterwards fail.
+func (a *actorConn) stop() {
+	a.once.Do(func() { close(a.done) })
+}
+
+// actorRow runs its query and scan on the actor goroutine.
+type actorRow struct {
+	a    *actorConn
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+func (r *actorRow) Scan(dest ...interface{}) error {
+	var err error
+	if derr := r.a.do(r.ctx, func() { err = r.a.conn.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...) }); derr != nil {
+		return derr
+	}
+	return err
+}
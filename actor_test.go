@@ -0,0 +1,77 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// exclusiveConn fails the test if it is used by two goroutines at once.
+type exclusiveConn struct {
+	*fakeSession
+	t      *testing.T
+	active atomic.Int32
+}
+
+func (c *exclusiveConn) enter() func() {
+	if c.active.Add(1) > 1 {
+		c.t.Error("connection used concurrently")
+	}
+	time.Sleep(time.Millisecond)
+	return func() { c.active.Add(-1) }
+}
+
+func TestActorConnSerializes(t *testing.T) {
+	c := &exclusiveConn{fakeSession: newFakeDB().session(), t: t}
+	c.hook = func(string) { defer c.enter()() }
+	a := newActorConn(c)
+	defer a.stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := a.Exec(context.Background(), "SELECT 1"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			var pid uint32
+			if err := a.QueryRow(context.Background(), "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestActorConnStopped(t *testing.T) {
+	a := newActorConn(newFakeDB().session())
+	a.stop()
+	a.stop()
+	if _, err := a.Exec(context.Background(), "SELECT 1"); !errors.Is(err, errActorStopped) {
+		t.Fatalf("Exec after stop = %v, want errActorStopped", err)
+	}
+}
+
+func TestWithConnectionActor(t *testing.T) {
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(135), WithConnectionActor())
+	if _, ok := m.conn.(*actorConn); !ok {
+		t.Fatalf("connection %T, want the actor", m.conn)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := m.IsHeld(context.Background()); err != nil || !held {
+		t.Fatalf("IsHeld = %t, %v", held, err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
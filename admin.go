@@ -0,0 +1,41 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+)
+
+// Unlock releases one exclusive hold of the advisory lock id on conn, without the Mutex
+// that took it. Reports whether the session held the lock. Session-level locks can only
+// be released by the session holding them, so this only works on the holding connection.
+// A Mutex of this process that still believes it holds the lock is not updated.
+func Unlock(ctx context.Context, conn Conn, id int64) (bool, error) {
+	var released bool
+	if err := conn.QueryRow(ctx, sqlUnlock, id).Scan(&released); err != nil {
+		return false, fmt.Errorf("failed to release lock: %w", err)
+	}
+	return released, nil
+}
+
+// UnlockAll releases all session-level advisory locks held by conn's session and returns
+// how many distinct advisory locks the session held before. Reentrant holds of one lock
+// count once, and transaction-level locks are counted but stay held until the transaction
+// ends. Like Unlock, it only affects the session of conn.
+func UnlockAll(ctx context.Context, conn Conn) (int, error) {
+	var held int
+	if err := conn.QueryRow(ctx, sqlHeldCount).Scan(&held); err != nil {
+		return 0, fmt.Errorf("failed to count held locks: %w", err)
+	}
+	if err := UnlockAllUncounted(ctx, conn); err != nil {
+		return 0, err
+	}
+	return held, nil
+}
+
+// UnlockAllUncounted is UnlockAll without the round-trip counting the held locks.
+func UnlockAllUncounted(ctx context.Context, conn Conn) error {
+	if _, err := conn.Exec(ctx, sqlUnlockAll); err != nil {
+		return fmt.Errorf("failed to release all locks: %w", err)
+	}
+	return nil
+}
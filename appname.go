@@ -0,0 +1,72 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+)
+
+const sqlSwapAppName = "SELECT current_setting('application_name'), set_config('application_name', $1, false)"
+
+// lockLabelKey is the context key of the label of LockLabeled.
+type lockLabelKey struct{}
+
+// LockLabeled acquires the lock like LockContext, setting application_name to label for
+// the duration of the hold, so that pg_stat_activity shows which unit of work holds or
+// waits for the lock. The previous application_name is restored once the Mutex no longer
+// holds any lock, or when the acquisition fails. Like WithApplicationNameFromContext,
+// only connections owned by the Mutex (WithConnStr or WithLockPool) are labeled, and the
+// label takes precedence over the name derived from the context.
+func (m *Mutex) LockLabeled(ctx context.Context, label string) error {
+	return m.LockWith(ctx, CallLabel(label))
+}
+
+// tagSession sets application_name to the label of LockLabeled or the name derived from
+// ctx by WithApplicationNameFromContext, so that pg_stat_activity shows which request
+// takes the lock. Connections not owned by the Mutex are left untouched.
+func (m *Mutex) tagSession(ctx context.Context) error {
+	if !m.ownsConn && m.pool == nil {
+		return nil
+	}
+	if label, _ := ctx.Value(lockLabelKey{}).(string); label != "" {
+		return m.labelSession(ctx, label)
+	}
+	if m.appName == nil {
+		return nil
+	}
+	name := m.appName(ctx)
+	if name == "" {
+		return nil
+	}
+	if _, err := m.exec(ctx, sqlSetAppName, name); err != nil {
+		return fmt.Errorf("failed to set application name: %w", m.classifyError(err))
+	}
+	return nil
+}
+
+// labelSession sets application_name to label, remembering the name it replaced unless
+// the session is already labeled.
+func (m *Mutex) labelSession(ctx context.Context, label string) error {
+	if m.labeled {
+		if _, err := m.exec(ctx, sqlSetAppName, label); err != nil {
+			return fmt.Errorf("failed to set application name: %w", m.classifyError(err))
+		}
+		return nil
+	}
+	var prev, cur string
+	if err := m.queryRow(ctx, sqlSwapAppName, label).Scan(&prev, &cur); err != nil {
+		return fmt.Errorf("failed to set application name: %w", m.classifyError(err))
+	}
+	m.labeled, m.labelPrev = true, prev
+	return nil
+}
+
+// restoreLabelLocked restores the application_name replaced by LockLabeled once no lock
+// is held. It is best effort, a failure leaves the label in place. Must be called with
+// m.m held.
+func (m *Mutex) restoreLabelLocked(ctx context.Context) {
+	if !m.labeled || m.held || m.shared > 0 || m.conn == nil {
+		return
+	}
+	m.labeled = false
+	_, _ = m.exec(ctx, sqlSetAppName, m.labelPrev)
+}
@@ -0,0 +1,27 @@
+package pgxmutex
+
+import "time"
+
+// AuditEvent records one lock operation for an audit trail. It marshals to JSON.
+type AuditEvent struct {
+	Operation  Operation `json:"operation"`
+	ResourceID int64     `json:"resource_id"`
+	// Time is when the operation started.
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	// Success is false for failed operations and for attempts that did not acquire the lock.
+	Success  bool              `json:"success"`
+	Err      error             `json:"-"`
+	Error    string            `json:"error,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// audit delivers e to the audit sink. A panicking sink never breaks the lock operation.
+func (m *Mutex) audit(e AuditEvent) {
+	if e.Err != nil {
+		e.Error = e.Err.Error()
+	}
+
+	defer func() { recover() }()
+	m.auditSink(e)
+}
@@ -0,0 +1,156 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AcquireWithBackoff polls TryLock until the lock is acquired or ctx is done.
+// The first attempt is immediate; the delay between attempts starts at base and
+// doubles up to maxDelay. Returns ErrLockNotAcquired when ctx expires.
+func (m *Mutex) AcquireWithBackoff(ctx context.Context, base, maxDelay time.Duration) error {
+	if base <= 0 || maxDelay < base {
+		return fmt.Errorf("invalid backoff: base must be positive and not greater than max delay")
+	}
+
+	start := time.Now()
+	delay := base
+	for attempt := 1; ; attempt++ {
+		acquired, err := m.TryLockContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: %w", ErrLockNotAcquired, ctx.Err())
+			}
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		m.logRetry(attempt, delay, start, nil)
+		if err := m.waitRetry(ctx, delay); err != nil {
+			return fmt.Errorf("%w: %w", ErrLockNotAcquired, err)
+		}
+
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// LockWithRetry polls TryLock up to attempts times, waiting between attempts as
+// determined by the backoff function of WithBackoff, exponential from 10ms up to 1s by
+// default. Returns ErrLockNotAcquired when all attempts fail or ctx is done.
+func (m *Mutex) LockWithRetry(ctx context.Context, attempts int) error {
+	if attempts < 1 {
+		return fmt.Errorf("attempts must be positive")
+	}
+
+	backoff := m.backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		acquired, err := m.TryLockContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: %w", ErrLockNotAcquired, ctx.Err())
+			}
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if attempt == attempts {
+			return ErrLockNotAcquired
+		}
+
+		delay := backoff(attempt)
+		m.logRetry(attempt, delay, start, nil)
+		if err := m.waitRetry(ctx, delay); err != nil {
+			return fmt.Errorf("%w: %w", ErrLockNotAcquired, err)
+		}
+	}
+}
+
+// AcquireWithin takes the lock with blocking attempts whose lock_timeout is what is left
+// of total, so that waiting and retrying together never take longer than total. Attempts
+// aborted by a deadlock or a lost connection are retried after the WithBackoff delay.
+// Returns ErrLockTimeout when the budget is exhausted.
+func (m *Mutex) AcquireWithin(ctx context.Context, total time.Duration) error {
+	if total <= 0 {
+		return fmt.Errorf("total wait must be positive")
+	}
+
+	backoff := m.backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+
+	start := time.Now()
+	deadline := start.Add(total)
+	for attempt := 1; ; attempt++ {
+		if !time.Now().Before(deadline) {
+			return ErrLockTimeout
+		}
+
+		actx, cancel := context.WithDeadline(ctx, deadline)
+		err := m.LockContext(actx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var deadlock *DeadlockError
+		switch {
+		case errors.Is(err, ErrLockTimeout), !time.Now().Before(deadline):
+			return ErrLockTimeout
+		case !errors.As(err, &deadlock) && !errors.Is(err, ErrConnectionLost):
+			return err
+		}
+
+		delay := backoff(attempt)
+		if left := time.Until(deadline); delay > left {
+			delay = left
+		}
+		m.logRetry(attempt, delay, start, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// defaultBackoff doubles the delay after each attempt, from 10ms up to 1s.
+func defaultBackoff(attempt int) time.Duration {
+	if attempt > 7 {
+		return time.Second
+	}
+	d := 10 * time.Millisecond << (attempt - 1)
+	if d > time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// sleepContext waits for d or until ctx is done. A non-positive d only checks ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquireWithinTimesOut(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 159)
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(159))
+
+	start := time.Now()
+	if err := m.AcquireWithin(context.Background(), 60*time.Millisecond); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("AcquireWithin on a held lock = %v, want ErrLockTimeout", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("AcquireWithin took %v for a 60ms budget", d)
+	}
+}
+
+func TestAcquireWithinRetriesDeadlocks(t *testing.T) {
+	s := deadlockSession(2)
+	m := newTestMutex(t, WithConn(s), WithResourceID(159), WithBackoff(func(int) time.Duration { return time.Millisecond }))
+
+	if err := m.AcquireWithin(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("AcquireWithin = %v", err)
+	}
+	if n := s.count(sqlLock); n != 3 {
+		t.Errorf("%d attempts, want 3", n)
+	}
+	// Every attempt ran with lock_timeout set to what was left of the budget
+	if n := s.count("set_config('lock_timeout'"); n < 3 {
+		t.Errorf("lock_timeout set %d times, want once per attempt", n)
+	}
+}
+
+func TestAcquireWithinValidates(t *testing.T) {
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithResourceID(159))
+	if err := m.AcquireWithin(context.Background(), 0); err == nil {
+		t.Error("zero budget accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// readerSlotKey returns the advisory key of reader slot i of resource id.
+func readerSlotKey(id int64, i int) int64 {
+	return hashKey("pgxmutex/reader-slot/" + strconv.FormatInt(id, 10) + "/" + strconv.Itoa(i))
+}
+
+// BoundedReadLock takes the lock in shared mode like LockShared, and additionally one of
+// maxReaders reader slots, so that at most maxReaders holders of all processes read at
+// the same time while writers are still excluded by the shared lock. The slots are the
+// exclusive advisory keys hashed (FNV-1a, like WithResourceBytes) from
+// "pgxmutex/reader-slot/<resource ID>/<slot>" for slots 0 to maxReaders-1, which must
+// not be used for other locks. Waiting for a free slot polls like LockWithRetry, and
+// ErrLockNotAcquired is returned when ctx is done first. The returned function releases
+// the slot and the shared lock.
+//
+// Close releases the slots still held. Handoff moves them to the new Mutex, whose Close
+// releases them; the returned function then returns ErrLockNotHeld. Slots are tracked per
+// Mutex, so Mutexes sharing a session, e.g. those of a LockerFactory, take a slot held by
+// another one reentrantly and may together exceed maxReaders.
+func (m *Mutex) BoundedReadLock(ctx context.Context, maxReaders int) (func() error, error) {
+	if maxReaders < 1 {
+		return nil, fmt.Errorf("max readers must be positive")
+	}
+	if err := m.lockShared(ctx); err != nil {
+		return nil, err
+	}
+
+	backoff := m.backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		slot, err := m.takeReaderSlot(ctx, maxReaders)
+		if err == nil && slot != 0 {
+			return m.readerRelease(slot), nil
+		}
+		if err == nil {
+			delay := backoff(attempt)
+			m.logRetry(attempt, delay, start, nil)
+			if werr := m.waitRetry(ctx, delay); werr != nil {
+				err = fmt.Errorf("%w: %w", ErrLockNotAcquired, werr)
+			}
+		}
+		if err != nil {
+			_, _ = m.unlockShared(m.context())
+			return nil, err
+		}
+	}
+}
+
+// takeReaderSlot tries each reader slot in turn and returns the key of the one taken,
+// or 0 if all are busy. Slots held by this Mutex are skipped, as the session would take
+// them again reentrantly.
+func (m *Mutex) takeReaderSlot(ctx context.Context, maxReaders int) (int64, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	for i := 0; i < maxReaders; i++ {
+		key := readerSlotKey(m.so.id, i)
+		if m.readerSlots[key] {
+			continue
+		}
+		var acquired bool
+		if err := m.queryRow(ctx, sqlTryLock, key).Scan(&acquired); err != nil {
+			return 0, fmt.Errorf("failed to attempt reader slot: %w", m.classifyError(err))
+		}
+		if acquired {
+			if m.readerSlots == nil {
+				m.readerSlots = make(map[int64]bool)
+			}
+			m.readerSlots[key] = true
+			return key, nil
+		}
+	}
+	return 0, nil
+}
+
+// readerRelease returns the function releasing a reader slot and its shared lock once.
+func (m *Mutex) readerRelease(slot int64) func() error {
+	var once sync.Once
+	var err error
+	return func() error {
+		once.Do(func() {
+			ctx := m.context()
+			m.m.Lock()
+			if !m.readerSlots[slot] {
+				// Moved by Handoff or released by Close
+				m.m.Unlock()
+				err = ErrLockNotHeld
+				return
+			}
+			_, uerr := m.exec(ctx, sqlUnlock, slot)
+			delete(m.readerSlots, slot)
+			m.m.Unlock()
+			if uerr != nil {
+				err = fmt.Errorf("failed to release reader slot: %w", m.classifyError(uerr))
+				return
+			}
+			_, err = m.unlockShared(ctx)
+		})
+		return err
+	}
+}
+
+// releaseReaderSlots releases the reader slots held by m. Must be called with m.m held.
+func (m *Mutex) releaseReaderSlots(ctx context.Context) error {
+	var err error
+	for slot := range m.readerSlots {
+		if _, uerr := m.exec(ctx, sqlUnlock, slot); uerr != nil && err == nil {
+			err = fmt.Errorf("failed to release reader slot: %w", m.classifyError(uerr))
+		}
+		delete(m.readerSlots, slot)
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker of WithCircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets acquisitions through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects acquisitions with ErrCircuitOpen until the cooldown ends.
+	CircuitOpen
+	// CircuitHalfOpen lets one probing acquisition through, whose outcome closes or
+	// reopens the circuit.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerSettings configures WithCircuitBreaker.
+type CircuitBreakerSettings struct {
+	// Failures is the number of consecutive failed acquisitions that opens the circuit.
+	Failures int
+	// Cooldown is how long the circuit stays open before a probe is let through.
+	Cooldown time.Duration
+}
+
+// circuitBreaker counts consecutive failed acquisitions and rejects acquisitions while open.
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports ErrCircuitOpen if an acquisition must not reach the database.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.settings.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+	default:
+		return nil
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of an acquisition that passed allow and reached the
+// database. Finding the lock taken is not a failure, and neither is a cancellation by
+// the caller, which, like pass, hands the half-open probe on to the next acquisition.
+func (b *circuitBreaker) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		b.pass()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.state, b.failures, b.probing = CircuitClosed, 0, false
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.settings.Failures {
+		b.state, b.failures, b.probing = CircuitOpen, 0, false
+		b.openedAt = time.Now()
+	}
+}
+
+// pass ends an acquisition that passed allow but never reached the database without
+// counting it. If it was the half-open probe, the next acquisition probes instead.
+func (b *circuitBreaker) pass() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen && b.probing {
+		// openedAt is kept, so the next acquisition probes right away
+		b.state, b.probing = CircuitOpen, false
+	}
+}
+
+// recordBreaker counts the outcome of an acquisition that pinned a connection with the
+// circuit breaker, if any.
+func (m *Mutex) recordBreaker(err error) {
+	if m.breaker != nil {
+		m.breaker.record(err)
+	}
+}
+
+// CircuitState returns the state of the circuit breaker of WithCircuitBreaker, or
+// CircuitClosed if none is configured.
+func (m *Mutex) CircuitState() CircuitState {
+	if m.breaker == nil {
+		return CircuitClosed
+	}
+	m.breaker.mu.Lock()
+	defer m.breaker.mu.Unlock()
+	if m.breaker.state == CircuitOpen && time.Since(m.breaker.openedAt) >= m.breaker.settings.Cooldown {
+		return CircuitHalfOpen
+	}
+	return m.breaker.state
+}
+
+// WithCircuitBreaker stops acquisitions from reaching the database after
+// settings.Failures consecutive failed ones, e.g. lock timeouts or connection errors
+// while the database is overloaded. They fail with ErrCircuitOpen for settings.Cooldown,
+// after which one acquisition probes the database and closes the circuit on success.
+// The breaker is created with the option, so Mutexes of a LockerFactory share it.
+func WithCircuitBreaker(settings CircuitBreakerSettings) Option {
+	b := &circuitBreaker{settings: settings}
+	return func(m *Mutex) error {
+		if settings.Failures < 1 || settings.Cooldown <= 0 {
+			return fmt.Errorf("invalid circuit breaker: failures and cooldown must be positive")
+		}
+		m.breaker = b
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensOnDatabaseFailures(t *testing.T) {
+	s := newFakeDB().session()
+	s.fail = map[string]error{"pg_advisory_lock(": errors.New("too many connections")}
+	m := newTestMutex(t, WithConn(s), WithResourceID(197), WithCircuitBreaker(CircuitBreakerSettings{Failures: 2, Cooldown: time.Hour}))
+
+	for i := 0; i < 2; i++ {
+		if err := m.Lock(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Lock %d = %v, want the database error", i, err)
+		}
+	}
+	if err := m.Lock(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Lock = %v, want ErrCircuitOpen", err)
+	}
+	if n := s.count("pg_advisory_lock("); n != 2 {
+		t.Errorf("%d lock statements, want none while open", n)
+	}
+}
+
+func TestCircuitBreakerIgnoresAttemptsWithoutRoundTrip(t *testing.T) {
+	db := newFakeDB()
+	breaker := WithCircuitBreaker(CircuitBreakerSettings{Failures: 1, Cooldown: time.Hour})
+	holder := newTestMutex(t, WithConn(db.session()), WithResourceID(197), breaker)
+	if err := holder.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither failures nor successes: a done ctx, a passed deadline, a local holder
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(197), breaker)
+	if err := m.LockContext(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LockContext = %v, want context.DeadlineExceeded", err)
+	}
+	late := newTestMutex(t, WithConn(db.session()), WithResourceID(198), breaker, WithDeadline(time.Now().Add(-time.Second)))
+	if err := late.Lock(); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Lock = %v, want ErrLockTimeout", err)
+	}
+	if state := m.CircuitState(); state != CircuitClosed {
+		t.Fatalf("circuit %v after attempts without a round trip, want closed", state)
+	}
+
+	// Open the circuit, which a locally blocked attempt must not close again
+	failing := db.session()
+	failing.fail = map[string]error{"pg_advisory_lock(": errors.New("too many connections")}
+	f := newTestMutex(t, WithConn(failing), WithResourceID(199), breaker)
+	if err := f.Lock(); err == nil {
+		t.Fatal("Lock succeeded on a failing session")
+	}
+	if outcome, err := m.TryLockDetailed(context.Background()); err != nil || outcome != BlockedLocally {
+		t.Fatalf("TryLockDetailed = %v, %v, want blocked locally", outcome, err)
+	}
+	if state := m.CircuitState(); state != CircuitOpen {
+		t.Errorf("circuit %v after a locally blocked attempt, want open", state)
+	}
+}
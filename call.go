@@ -0,0 +1,78 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CallOption overrides the configuration of a Mutex for a single LockWith call.
+type CallOption func(*callOptions)
+
+// callOptions is the configuration of a single LockWith call.
+type callOptions struct {
+	mode    LockMode
+	timeout time.Duration
+	label   string
+}
+
+// CallMode takes the lock in mode instead of the mode set with WithLockMode. Unlock
+// releases it whatever the WithLockMode mode. The lock scope cannot be overridden per
+// call: transaction-level locks need a connection in a transaction, so the scope is set
+// per Mutex with WithLockScope.
+func CallMode(mode LockMode) CallOption {
+	return func(co *callOptions) {
+		co.mode = mode
+	}
+}
+
+// CallTimeout bounds the wait for the lock to d, applied as lock_timeout like a ctx
+// deadline. ErrLockTimeout is returned when it expires.
+func CallTimeout(d time.Duration) CallOption {
+	return func(co *callOptions) {
+		co.timeout = d
+	}
+}
+
+// CallLabel sets application_name to label for the duration of the hold, like LockLabeled.
+func CallLabel(label string) CallOption {
+	return func(co *callOptions) {
+		co.label = label
+	}
+}
+
+// LockWith acquires the lock like LockContext with options overriding the configuration
+// of the Mutex for this call only. Call options take precedence over construction
+// options, except that a CallTimeout cannot extend a WithDeadline deadline or a ctx
+// deadline: the earliest of them applies.
+func (m *Mutex) LockWith(ctx context.Context, options ...CallOption) error {
+	co := callOptions{mode: m.mode}
+	for _, opt := range options {
+		opt(&co)
+	}
+
+	lock := m.lockExclusive
+	switch co.mode {
+	case ModeExclusive:
+	case ModeShared:
+		lock = m.lockShared
+	default:
+		return fmt.Errorf("unknown lock mode %d", co.mode)
+	}
+	if co.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, co.timeout)
+		defer cancel()
+	}
+	if co.label == "" {
+		return m.retryDeadlocks(ctx, lock)
+	}
+
+	err := m.retryDeadlocks(context.WithValue(ctx, lockLabelKey{}, co.label), lock)
+	if err != nil {
+		m.m.Lock()
+		m.restoreLabelLocked(m.ctx)
+		m.m.Unlock()
+	}
+	return err
+}
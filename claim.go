@@ -0,0 +1,45 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// claimNamespace is prefixed to idempotency keys before hashing so that claims do not
+// collide with other string-derived keys.
+const claimNamespace = "pgxmutex:claim:"
+
+// TryClaim tries without blocking to take the exclusive lock derived from
+// idempotencyKey and reports whether this consumer won the right to process the message.
+// The winner calls release once processing has finished; for losers it does nothing, so
+// that it can always be deferred.
+//
+// The claim is a session-level lock on the shared connection of the factory, so it lasts
+// as long as that connection does: transaction scope is rejected, and the connection
+// must stay open until processing ends. A claim only prevents concurrent processing;
+// recording that a message has been processed is left to the caller.
+func (f *LockerFactory) TryClaim(ctx context.Context, idempotencyKey string) (won bool, release func() error, err error) {
+	m, err := f.Mutex(ClaimKey(idempotencyKey))
+	if err != nil {
+		return false, noRelease, err
+	}
+	if m.stmts.unlock == "" {
+		return false, noRelease, fmt.Errorf("claims require session-level locks")
+	}
+	if won, err = m.TryLockContext(ctx); err != nil || !won {
+		return false, noRelease, err
+	}
+
+	var once sync.Once
+	var uerr error
+	return true, func() error {
+		once.Do(func() { uerr = m.UnlockContext(m.context()) })
+		return uerr
+	}, nil
+}
+
+// ClaimKey returns the advisory lock key used by TryClaim for idempotencyKey.
+func ClaimKey(idempotencyKey string) int64 {
+	return hashKey(claimNamespace + idempotencyKey)
+}
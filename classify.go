@@ -0,0 +1,76 @@
+package pgxmutex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// LockErrorKind is the package-level meaning of a database error.
+type LockErrorKind int
+
+const (
+	// ErrorKindOther is an error with no special meaning to the package.
+	ErrorKindOther LockErrorKind = iota
+	// ErrorKindTimeout means the lock wait was cut short by a timeout; mapped to ErrLockTimeout.
+	ErrorKindTimeout
+	// ErrorKindNotAcquired means the lock was refused; mapped to ErrLockNotAcquired.
+	ErrorKindNotAcquired
+	// ErrorKindConnectionLost means the session is gone, and with it any lock it held; mapped to ErrConnectionLost.
+	ErrorKindConnectionLost
+)
+
+// DefaultErrorClassifier classifies errors by standard PostgreSQL SQLSTATEs:
+// 55P03 (lock_not_available) and 57014 (query_canceled) are timeouts, class 08
+// (connection exception), 57P01 (admin_shutdown) and errors raised before the
+// server could respond on a closed connection mean the connection is lost.
+func DefaultErrorClassifier(err error) LockErrorKind {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == sqlStateLockNotAvailable, pgErr.Code == "57014":
+			return ErrorKindTimeout
+		case pgErr.Code == "57P01", len(pgErr.Code) == 5 && pgErr.Code[:2] == "08":
+			return ErrorKindConnectionLost
+		}
+		return ErrorKindOther
+	}
+	// Context deadlines are left to the caller's context handling.
+	if pgconn.Timeout(err) {
+		return ErrorKindOther
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return ErrorKindConnectionLost
+	}
+	return ErrorKindOther
+}
+
+// classifyError wraps err with the package sentinel matching its kind.
+func (m *Mutex) classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	classifier := m.classifier
+	if classifier == nil {
+		classifier = DefaultErrorClassifier
+	}
+
+	var sentinel error
+	switch classifier(err) {
+	case ErrorKindTimeout:
+		sentinel = ErrLockTimeout
+	case ErrorKindNotAcquired:
+		sentinel = ErrLockNotAcquired
+	case ErrorKindConnectionLost:
+		sentinel = ErrConnectionLost
+	default:
+		return err
+	}
+	if errors.Is(err, sentinel) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
@@ -0,0 +1,70 @@
+package pgxmutex
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// coalesced reports whether a recent attempt of this process found the lock taken by
+// another session within the coalescing window, so the attempt can be answered
+// without a round-trip.
+func (m *Mutex) coalesced(busyUntil *atomic.Int64) bool {
+	return m.coalesce > 0 && time.Now().UnixNano() < busyUntil.Load()
+}
+
+// markBusy starts a coalescing window after an attempt found the lock taken.
+func (m *Mutex) markBusy(busyUntil *atomic.Int64) {
+	if m.coalesce > 0 {
+		busyUntil.Store(time.Now().Add(m.coalesce).UnixNano())
+	}
+}
+
+// tryFlight is an attempt in flight whose result concurrent attempts wait for.
+type tryFlight struct {
+	done chan struct{}
+	busy bool
+}
+
+// joinTry makes the caller lead the next attempt in flight in slot, or waits for the one
+// already in flight. busy reports that the awaited attempt found the lock taken, which
+// answers the caller; otherwise, e.g. if that attempt took the lock, the caller tries to
+// lead the next attempt. A leader gets the flight to end with endTry. Without coalescing
+// it returns neither.
+func (m *Mutex) joinTry(ctx context.Context, slot **tryFlight) (lead *tryFlight, busy bool, err error) {
+	if m.coalesce <= 0 {
+		return nil, false, nil
+	}
+	for {
+		m.so.flightMu.Lock()
+		f := *slot
+		if f == nil {
+			f = &tryFlight{done: make(chan struct{})}
+			*slot = f
+			m.so.flightMu.Unlock()
+			return f, false, nil
+		}
+		m.so.flightMu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if f.busy {
+			return nil, true, nil
+		}
+	}
+}
+
+// endTry ends the attempt f led by the caller, handing busy to the attempts waiting for it.
+func (m *Mutex) endTry(slot **tryFlight, f *tryFlight, busy *bool) {
+	if f == nil {
+		return
+	}
+	m.so.flightMu.Lock()
+	f.busy = *busy
+	*slot = nil
+	m.so.flightMu.Unlock()
+	close(f.done)
+}
@@ -0,0 +1,129 @@
+package pgxmutex
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// holdFake takes the exclusive lock of id on a fresh session of db.
+func holdFake(t testing.TB, db *fakeDB, id int64) {
+	t.Helper()
+	if _, err := db.session().Exec(context.Background(), sqlLock, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTryLockCoalescingSharesInFlightResult(t *testing.T) {
+	const id = 131
+	db := newFakeDB()
+	holdFake(t, db, id)
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	lead := db.session()
+	lead.hook = func(sql string) {
+		if sql == sqlTryLockShared {
+			close(entered)
+			<-release
+		}
+	}
+	leader := newTestMutex(t, WithConn(lead), WithResourceID(id), WithTryLockCoalescing(time.Minute))
+
+	var wg sync.WaitGroup
+	var acquired atomic.Int64
+	try := func(m *Mutex) {
+		defer wg.Done()
+		ok, err := m.TryLockShared()
+		if err != nil {
+			t.Error(err)
+		}
+		if ok {
+			acquired.Add(1)
+		}
+	}
+	wg.Add(1)
+	go try(leader)
+	<-entered
+
+	var followers []*fakeSession
+	for i := 0; i < 8; i++ {
+		s := db.session()
+		followers = append(followers, s)
+		wg.Add(1)
+		go try(newTestMutex(t, WithConn(s), WithResourceID(id), WithTryLockCoalescing(time.Minute)))
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := acquired.Load(); n != 0 {
+		t.Fatalf("%d attempts acquired a lock held exclusively by another session", n)
+	}
+	for i, s := range followers {
+		if n := s.count("pg_try_advisory_lock_shared"); n != 0 {
+			t.Errorf("follower %d ran %d attempts, want the in-flight result shared", i, n)
+		}
+	}
+}
+
+func TestTryLockCoalescingRetriesAfterAcquiredFlight(t *testing.T) {
+	const id = 132
+	db := newFakeDB()
+	a := newTestMutex(t, WithConn(db.session()), WithResourceID(id), WithTryLockCoalescing(time.Minute))
+	b := newTestMutex(t, WithConn(db.session()), WithResourceID(id), WithTryLockCoalescing(time.Minute))
+
+	if ok, err := a.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock = %t, %v", ok, err)
+	}
+	outcome, err := b.TryLockDetailed(context.Background())
+	if err != nil || outcome != BlockedLocally {
+		t.Fatalf("TryLockDetailed = %v, %v, want BlockedLocally", outcome, err)
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := b.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock after release = %t, %v", ok, err)
+	}
+}
+
+// BenchmarkTryLockCoalescing reports the round trips per attempt of concurrent TryLock
+// calls on a lock held by another session, with and without coalescing.
+func BenchmarkTryLockCoalescing(b *testing.B) {
+	for _, window := range []time.Duration{0, time.Millisecond} {
+		b.Run("window="+window.String(), func(b *testing.B) {
+			db := newFakeDB()
+			holdFake(b, db, benchmarkResourceID)
+			var mu sync.Mutex
+			var sessions []*fakeSession
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				s := db.session()
+				mu.Lock()
+				sessions = append(sessions, s)
+				mu.Unlock()
+				options := []Option{WithConn(s), WithResourceID(benchmarkResourceID)}
+				if window > 0 {
+					options = append(options, WithTryLockCoalescing(window))
+				}
+				m := newTestMutex(b, options...)
+				for pb.Next() {
+					if _, err := m.TryLockShared(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+
+			queries := 0
+			for _, s := range sessions {
+				queries += s.count("pg_try_advisory_lock_shared")
+			}
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/op")
+		})
+	}
+}
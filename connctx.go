@@ -0,0 +1,57 @@
+package pgxmutex
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// WithConnFromContext makes the Mutex resolve the connection of each statement from the
+// context of the operation with fn, e.g. the transaction a unit-of-work middleware keeps
+// in the request context, so that locks run where the request's work runs. Operations
+// without a context argument use the Mutex context. Errors of fn fail the statement.
+//
+// Combined with WithLockScope(ScopeXact), locks join the request transaction and are
+// released when it ends. With session-level locks, fn must return the same session for
+// the acquisition and the release, otherwise Unlock misses the session holding the lock.
+// Replaces WithConn and WithConnStr.
+func WithConnFromContext(fn func(ctx context.Context) (Conn, error)) Option {
+	return func(m *Mutex) error {
+		m.conn = &ctxConn{resolve: fn}
+		m.conns = nil
+		m.connStr = ""
+		m.ownsConn = false
+		return nil
+	}
+}
+
+// ctxConn runs each statement on the connection resolved from its context.
+type ctxConn struct {
+	resolve func(ctx context.Context) (Conn, error)
+}
+
+func (c *ctxConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	conn, err := c.resolve(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return conn.Exec(ctx, sql, arguments...)
+}
+
+func (c *ctxConn) QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row {
+	conn, err := c.resolve(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return conn.QueryRow(ctx, sql, optionsAndArgs...)
+}
+
+// errRow is a row failing to scan with err.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
@@ -0,0 +1,45 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// connect dials a new connection. It is a variable so the dial can be replaced in tests.
+var connect = pgx.Connect
+
+// dial connects to m.connStr, retrying as configured by WithConnectRetry.
+func (m *Mutex) dial(ctx context.Context) (*pgx.Conn, error) {
+	attempts := m.connectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := m.connectDelay
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, fmt.Errorf("failed to connect to database: %w", err)
+			case <-t.C:
+			}
+			delay *= 2
+		}
+
+		var c *pgx.Conn
+		if c, err = connect(ctx, m.connStr); err == nil {
+			return c, nil
+		}
+	}
+
+	if attempts > 1 {
+		return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", attempts, err)
+	}
+	return nil, fmt.Errorf("failed to connect to database: %w", err)
+}
@@ -0,0 +1,86 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// stubConnect replaces connect for the test with fn.
+func stubConnect(t *testing.T, fn func(ctx context.Context, connStr string) (*pgx.Conn, error)) {
+	t.Helper()
+	prev := connect
+	connect = fn
+	t.Cleanup(func() { connect = prev })
+}
+
+func TestWithConnectRetry(t *testing.T) {
+	errDown := errors.New("database is starting up")
+	tests := []struct {
+		name     string
+		attempts int
+		failures int
+		wantErr  string
+	}{
+		{name: "no retry", attempts: 1, failures: 1, wantErr: "failed to connect to database: database is starting up"},
+		{name: "recovers", attempts: 3, failures: 2},
+		{name: "exhausted", attempts: 3, failures: 3, wantErr: "after 3 attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			stubConnect(t, func(ctx context.Context, connStr string) (*pgx.Conn, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, errDown
+				}
+				return nil, nil
+			})
+
+			m := &Mutex{connStr: "postgres://localhost/none"}
+			if err := WithConnectRetry(tt.attempts, time.Millisecond)(m); err != nil {
+				t.Fatal(err)
+			}
+			_, err := m.dial(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("dial = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, errDown) {
+				t.Fatalf("dial = %v, want %q wrapping the last error", err, tt.wantErr)
+			}
+			if want := min(tt.failures+1, tt.attempts); calls != want {
+				t.Errorf("%d dial attempts, want %d", calls, want)
+			}
+		})
+	}
+}
+
+func TestWithConnectRetryStopsOnContext(t *testing.T) {
+	stubConnect(t, func(ctx context.Context, connStr string) (*pgx.Conn, error) {
+		return nil, errors.New("refused")
+	})
+	m := &Mutex{connStr: "postgres://localhost/none"}
+	if err := WithConnectRetry(5, time.Hour)(m); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.dial(ctx); err == nil {
+		t.Fatal("dial succeeded")
+	}
+}
+
+func TestWithConnectRetryValidates(t *testing.T) {
+	if err := WithConnectRetry(0, time.Second)(&Mutex{}); err == nil {
+		t.Error("zero attempts accepted")
+	}
+	if err := WithConnectRetry(1, -time.Second)(&Mutex{}); err == nil {
+		t.Error("negative delay accepted")
+	}
+}
@@ -0,0 +1,57 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sqlStateDeadlockDetected is the SQLSTATE of a statement aborted to break a deadlock.
+const sqlStateDeadlockDetected = "40P01"
+
+// DeadlockError is returned when PostgreSQL aborted the lock wait to break a deadlock,
+// typically between sessions taking several locks in different orders. Retrying after a
+// random delay usually succeeds.
+type DeadlockError struct {
+	ResourceID int64
+	Err        error
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf("deadlock detected on lock %d: %v", e.ResourceID, e.Err)
+}
+
+func (e *DeadlockError) Unwrap() error {
+	return e.Err
+}
+
+// deadlockError wraps err as a DeadlockError if it reports a deadlock.
+func (m *Mutex) deadlockError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == sqlStateDeadlockDetected {
+		return &DeadlockError{ResourceID: m.so.id, Err: err}
+	}
+	return err
+}
+
+// retryDeadlocks calls lock, retrying up to the WithDeadlockRetry attempts with a jittered
+// delay after each deadlock.
+func (m *Mutex) retryDeadlocks(ctx context.Context, lock func(context.Context) error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := lock(ctx)
+		var deadlock *DeadlockError
+		if attempt > m.deadlockRetries || !errors.As(err, &deadlock) {
+			return err
+		}
+		delay := time.Duration(rand.Int64N(int64(defaultBackoff(attempt))))
+		m.logRetry(attempt, delay, start, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
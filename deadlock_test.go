@@ -0,0 +1,74 @@
+package pgxmutex
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// deadlockSession returns a session whose first n lock statements fail with a deadlock.
+func deadlockSession(n int) *fakeSession {
+	s := newFakeDB().session()
+	deadlock := &pgconn.PgError{Code: sqlStateDeadlockDetected, Message: "deadlock detected"}
+	s.fail = map[string]error{sqlLock: deadlock}
+	s.hook = func(sql string) {
+		if sql != sqlLock {
+			return
+		}
+		if n == 0 {
+			s.fail = nil
+		}
+		n--
+	}
+	return s
+}
+
+func TestDeadlockError(t *testing.T) {
+	m := newTestMutex(t, WithConn(deadlockSession(1)), WithResourceID(149))
+
+	err := m.Lock()
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("Lock = %v, want a DeadlockError", err)
+	}
+	if deadlock.ResourceID != 149 {
+		t.Errorf("resource ID %d, want 149", deadlock.ResourceID)
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != sqlStateDeadlockDetected {
+		t.Errorf("DeadlockError does not wrap the PostgreSQL error: %v", err)
+	}
+}
+
+func TestWithDeadlockRetry(t *testing.T) {
+	tests := []struct {
+		deadlocks, retries int
+		wantDeadlock       bool
+	}{
+		{deadlocks: 2, retries: 2},
+		{deadlocks: 3, retries: 2, wantDeadlock: true},
+		{deadlocks: 1, retries: 0, wantDeadlock: true},
+	}
+	for _, tt := range tests {
+		s := deadlockSession(tt.deadlocks)
+		m := newTestMutex(t, WithConn(s), WithResourceID(149), WithDeadlockRetry(tt.retries))
+
+		err := m.Lock()
+		var deadlock *DeadlockError
+		if errors.As(err, &deadlock) != tt.wantDeadlock || (!tt.wantDeadlock && err != nil) {
+			t.Errorf("%d deadlocks, %d retries: Lock = %v", tt.deadlocks, tt.retries, err)
+		}
+		if want := min(tt.deadlocks, tt.retries) + 1; s.count(sqlLock) != want {
+			t.Errorf("%d deadlocks, %d retries: %d attempts, want %d", tt.deadlocks, tt.retries, s.count(sqlLock), want)
+		}
+		if err == nil {
+			if err := m.Unlock(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := WithDeadlockRetry(-1)(&Mutex{}); err == nil {
+		t.Error("negative retries accepted")
+	}
+}
@@ -0,0 +1,104 @@
+package pgxmutex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// LockDescriptor is the serializable configuration of a Mutex. It describes the
+// lock intent only and holds neither the connection nor any live lock state.
+type LockDescriptor struct {
+	ResourceID int64    `json:"resource_id"`
+	Mode       LockMode `json:"mode"`
+
+	// Scope and Functions are the namespace of the resource ID: the same ID names a
+	// different lock with other lock functions or arguments. Functions is nil for the
+	// built-in functions of Scope.
+	Scope     LockScope      `json:"scope"`
+	Functions *LockFunctions `json:"functions,omitempty"`
+}
+
+// Descriptor returns the serializable configuration of the Mutex.
+func (m *Mutex) Descriptor() LockDescriptor {
+	desc := LockDescriptor{ResourceID: m.so.id, Mode: m.mode, Scope: m.scope}
+	if m.fns != nil {
+		fns := *m.fns
+		fns.Args = append([]interface{}(nil), fns.Args...)
+		desc.Functions = &fns
+	}
+	return desc
+}
+
+// MarshalJSON encodes the Mutex descriptor.
+func (m *Mutex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Descriptor())
+}
+
+// UnmarshalJSON decodes a descriptor encoded by MarshalJSON. Numeric lock function
+// arguments are decoded as int64 if they are integers and as float64 otherwise.
+func (d *LockDescriptor) UnmarshalJSON(data []byte) error {
+	type plain LockDescriptor
+	var p plain
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	if p.Functions != nil {
+		for i, arg := range p.Functions.Args {
+			n, ok := arg.(json.Number)
+			if !ok {
+				continue
+			}
+			if v, err := n.Int64(); err == nil {
+				p.Functions.Args[i] = v
+			} else if v, err := n.Float64(); err == nil {
+				p.Functions.Args[i] = v
+			} else {
+				return fmt.Errorf("invalid lock function argument %s: %w", n, err)
+			}
+		}
+	}
+	*d = LockDescriptor(p)
+	return nil
+}
+
+// NewMutexFromDescriptor reconstructs a Mutex from a descriptor on the given connection.
+// Additional options are applied after the descriptor. An error is returned if they
+// change its namespace, as the Mutex would then lock another keyspace.
+func NewMutexFromDescriptor(conn Conn, desc LockDescriptor, options ...Option) (*Mutex, error) {
+	base := []Option{
+		WithConn(conn),
+		WithResourceID(desc.ResourceID),
+		WithLockMode(desc.Mode),
+		WithLockScope(desc.Scope),
+	}
+	if desc.Functions != nil {
+		base = append(base, WithLockFunctions(*desc.Functions))
+	}
+	m, err := NewMutex(append(base, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	if !desc.sameNamespace(m.Descriptor()) {
+		_ = m.Close()
+		return nil, fmt.Errorf("options change the lock namespace of the descriptor")
+	}
+	return m, nil
+}
+
+// sameNamespace reports whether d and o lock in the same namespace.
+func (d LockDescriptor) sameNamespace(o LockDescriptor) bool {
+	if d.Scope != o.Scope || (d.Functions == nil) != (o.Functions == nil) {
+		return false
+	}
+	if d.Functions == nil {
+		return true
+	}
+	a, b := *d.Functions, *o.Functions
+	return a.Lock == b.Lock && a.TryLock == b.TryLock && a.Unlock == b.Unlock &&
+		a.LockShared == b.LockShared && a.TryLockShared == b.TryLockShared &&
+		a.UnlockShared == b.UnlockShared && a.ArgsFirst == b.ArgsFirst &&
+		fmt.Sprint(a.Args) == fmt.Sprint(b.Args)
+}
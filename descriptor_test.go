@@ -0,0 +1,80 @@
+package pgxmutex
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDescriptorRoundTripKeepsNamespace(t *testing.T) {
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(111), WithLockMode(ModeShared), WithLockFunctions(tenantFunctions(7)))
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var desc LockDescriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		t.Fatal(err)
+	}
+	if desc.Mode != ModeShared || desc.Scope != ScopeSession || desc.Functions == nil {
+		t.Fatalf("decoded descriptor %+v from %s", desc, data)
+	}
+	if arg, ok := desc.Functions.Args[0].(int64); !ok || arg != 7 {
+		t.Errorf("decoded argument %#v, want int64(7)", desc.Functions.Args[0])
+	}
+
+	n, err := NewMutexFromDescriptor(db.session(), desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if n.namespace() != m.namespace() {
+		t.Errorf("namespace %q after reconstruction, want %q", n.namespace(), m.namespace())
+	}
+}
+
+func TestDescriptorScope(t *testing.T) {
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(111), WithLockScope(ScopeXact))
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"resource_id":111,"mode":"exclusive","scope":"xact"}`; string(data) != want {
+		t.Errorf("encoded %s, want %s", data, want)
+	}
+	var desc LockDescriptor
+	if err := json.Unmarshal(data, &desc); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewMutexFromDescriptor(db.session(), desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if n.stmts != xactStatements {
+		t.Error("reconstructed Mutex does not take transaction-level locks")
+	}
+}
+
+func TestNewMutexFromDescriptorRejectsNamespaceChange(t *testing.T) {
+	db := newFakeDB()
+	fns := tenantFunctions(7)
+	for name, tc := range map[string]struct {
+		desc   LockDescriptor
+		option Option
+	}{
+		"scope":     {LockDescriptor{ResourceID: 111}, WithLockScope(ScopeXact)},
+		"functions": {LockDescriptor{ResourceID: 111}, WithLockFunctions(fns)},
+		"arguments": {LockDescriptor{ResourceID: 111, Functions: &fns}, WithLockFunctions(tenantFunctions(8))},
+		"builtin":   {LockDescriptor{ResourceID: 111, Functions: &fns}, WithLockScope(ScopeSession)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewMutexFromDescriptor(db.session(), tc.desc, tc.option); err == nil {
+				t.Fatal("NewMutexFromDescriptor succeeded with options changing the namespace")
+			}
+		})
+	}
+}
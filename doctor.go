@@ -0,0 +1,144 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Names of the checks of a DiagnosticReport.
+const (
+	CheckLocksEnforced   = "advisory_locks_enforced"
+	CheckUnlockReturns   = "unlock_returns_true"
+	CheckNotReplica      = "not_replica"
+	CheckSessionAffinity = "session_affinity"
+)
+
+// DiagnosticCheck is the result of one check run by Doctor.
+type DiagnosticCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// DiagnosticReport lists the checks run by Doctor, e.g. for a readiness endpoint.
+type DiagnosticReport struct {
+	Checks []DiagnosticCheck `json:"checks"`
+}
+
+// OK reports whether no check failed. Skipped checks do not fail the report.
+func (r DiagnosticReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err returns an error naming the failed checks, or nil if none failed.
+func (r DiagnosticReport) Err() error {
+	var failed []string
+	for _, c := range r.Checks {
+		if !c.Passed && !c.Skipped {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Detail))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.New("diagnostic checks failed: " + strings.Join(failed, "; "))
+}
+
+// Doctor checks that the environment of the Mutex supports reliable advisory locks:
+// a held lock excludes a second session (skipped without WithConnStr or WithProbeConn),
+// unlocking a held lock reports true, the server is not a read replica, and consecutive
+// statements run on the same session, which transaction-pooling proxies break. Fresh
+// keys are used so that locks of the resource are not disturbed. It must not run
+// concurrently with other operations on the connection. With a lock pool all checks fail,
+// as no connection is checked out for them.
+func (m *Mutex) Doctor(ctx context.Context) DiagnosticReport {
+	var r DiagnosticReport
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.conn == nil {
+		detail := "no connection available"
+		if m.pool != nil {
+			detail = "not supported with a lock pool"
+		}
+		for _, name := range []string{CheckLocksEnforced, CheckUnlockReturns, CheckNotReplica, CheckSessionAffinity} {
+			r.Checks = append(r.Checks, DiagnosticCheck{Name: name, Detail: detail})
+		}
+		return r
+	}
+
+	if m.connStr == "" && m.probe == nil {
+		r.Checks = append(r.Checks, DiagnosticCheck{Name: CheckLocksEnforced, Skipped: true, Detail: "no second session available"})
+	} else {
+		r.Checks = append(r.Checks, diagnose(CheckLocksEnforced, m.checkEnforcement(ctx)))
+	}
+	r.Checks = append(r.Checks,
+		diagnose(CheckUnlockReturns, m.checkUnlockReturns(ctx)),
+		diagnose(CheckNotReplica, m.checkNotReplica(ctx)),
+		diagnose(CheckSessionAffinity, m.checkSessionAffinity(ctx)),
+	)
+	return r
+}
+
+// SelfTest runs Doctor and returns the error of its report.
+func (m *Mutex) SelfTest(ctx context.Context) error {
+	return m.Doctor(ctx).Err()
+}
+
+// diagnose turns the error of a check into its result.
+func diagnose(name string, err error) DiagnosticCheck {
+	if err != nil {
+		return DiagnosticCheck{Name: name, Detail: err.Error()}
+	}
+	return DiagnosticCheck{Name: name, Passed: true}
+}
+
+// checkUnlockReturns takes and releases a fresh lock. Must be called with m.m held.
+func (m *Mutex) checkUnlockReturns(ctx context.Context) error {
+	key := time.Now().UnixNano()
+	var acquired, released bool
+	if err := m.queryRow(ctx, sqlTryLock, key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to take a fresh lock: %w", err)
+	}
+	if !acquired {
+		return fmt.Errorf("a fresh lock could not be taken")
+	}
+	if err := m.queryRow(ctx, sqlUnlock, key).Scan(&released); err != nil {
+		return fmt.Errorf("failed to release a fresh lock: %w", err)
+	}
+	if !released {
+		return fmt.Errorf("unlock of a held lock returned false")
+	}
+	return nil
+}
+
+// checkNotReplica verifies that the server is not in recovery. Must be called with m.m held.
+func (m *Mutex) checkNotReplica(ctx context.Context) error {
+	var inRecovery bool
+	if err := m.queryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return fmt.Errorf("failed to query recovery state: %w", err)
+	}
+	if inRecovery {
+		return fmt.Errorf("server is a read replica, its locks do not coordinate with the primary")
+	}
+	return nil
+}
+
+// checkSessionAffinity verifies that two consecutive statements run on the same backend.
+// Must be called with m.m held.
+func (m *Mutex) checkSessionAffinity(ctx context.Context) error {
+	var first, second uint32
+	if err := m.queryRow(ctx, "SELECT pg_backend_pid()").Scan(&first); err != nil {
+		return fmt.Errorf("failed to query backend pid: %w", err)
+	}
+	if err := m.queryRow(ctx, "SELECT pg_backend_pid()").Scan(&second); err != nil {
+		return fmt.Errorf("failed to query backend pid: %w", err)
+	}
+	if first != second {
+		return fmt.Errorf("statements ran on different sessions, e.g. behind a transaction-pooling proxy")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(db *fakeDB, conn, probe *fakeSession)
+		failed []string
+	}{
+		{
+			name: "healthy",
+		},
+		{
+			name: "not enforced",
+			setup: func(db *fakeDB, conn, probe *fakeSession) {
+				probe.fail = map[string]error{"pg_try_advisory_lock": errors.New("boom")}
+			},
+			failed: []string{CheckLocksEnforced},
+		},
+		{
+			name:   "unlock returns false",
+			setup:  func(db *fakeDB, conn, probe *fakeSession) { conn.unlockFalse = true },
+			failed: []string{CheckUnlockReturns},
+		},
+		{
+			name:   "replica",
+			setup:  func(db *fakeDB, conn, probe *fakeSession) { db.inRecovery = true },
+			failed: []string{CheckNotReplica},
+		},
+		{
+			name:   "transaction pooling",
+			setup:  func(db *fakeDB, conn, probe *fakeSession) { conn.pidDrift = true },
+			failed: []string{CheckSessionAffinity},
+		},
+		{
+			name: "several",
+			setup: func(db *fakeDB, conn, probe *fakeSession) {
+				db.inRecovery = true
+				conn.pidDrift = true
+			},
+			failed: []string{CheckNotReplica, CheckSessionAffinity},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB()
+			conn, probe := db.session(), db.session()
+			if tt.setup != nil {
+				tt.setup(db, conn, probe)
+			}
+			m := newTestMutex(t, WithConn(conn), WithProbeConn(probe), WithResourceID(1))
+
+			r := m.Doctor(context.Background())
+			if len(r.Checks) != 4 {
+				t.Fatalf("got %d checks, want 4", len(r.Checks))
+			}
+			failed := make(map[string]bool)
+			for _, name := range tt.failed {
+				failed[name] = true
+			}
+			for _, c := range r.Checks {
+				if c.Passed == failed[c.Name] || c.Skipped {
+					t.Errorf("check %s: passed %t, skipped %t, detail %q", c.Name, c.Passed, c.Skipped, c.Detail)
+				}
+			}
+			if r.OK() != (len(tt.failed) == 0) {
+				t.Errorf("OK() = %t, err %v", r.OK(), r.Err())
+			}
+		})
+	}
+}
+
+func TestDoctorSkipsEnforcementWithoutSecondSession(t *testing.T) {
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithResourceID(1))
+
+	r := m.Doctor(context.Background())
+	if !r.OK() {
+		t.Fatalf("report failed: %v", r.Err())
+	}
+	if c := r.Checks[0]; c.Name != CheckLocksEnforced || !c.Skipped {
+		t.Errorf("first check %+v, want skipped enforcement check", c)
+	}
+}
+
+func TestDoctorWithLockPool(t *testing.T) {
+	m := &Mutex{ctx: context.Background(), stmts: defaultStatements, so: &singleton{id: 1}, probe: newFakeDB().session(), pool: &lockPool{maxIdle: 1}}
+
+	r := m.Doctor(context.Background())
+	if r.OK() {
+		t.Fatal("report passed with a lock pool")
+	}
+	for _, c := range r.Checks {
+		if c.Passed || c.Skipped {
+			t.Errorf("check %s passed or was skipped with a lock pool", c.Name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pgxmutex
+
+import "context"
+
+// DrainAndClose shuts the Mutex down gracefully. New lock attempts fail with ErrClosing
+// from the moment it is called, including attempts queued behind a local holder. It
+// then waits until the locks held and acquisitions in flight at that moment are
+// released or have failed, or until ctx is done, and finally closes the Mutex like
+// Close, releasing anything still held.
+func (m *Mutex) DrainAndClose(ctx context.Context) error {
+	m.m.Lock()
+	m.closing.Store(true)
+	var drained chan struct{}
+	if !m.idle() {
+		if m.drained == nil {
+			m.drained = make(chan struct{})
+		}
+		drained = m.drained
+	}
+	m.m.Unlock()
+
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+		}
+	}
+	return m.Close()
+}
+
+// beginAcquire counts an acquisition in flight unless the Mutex is closing.
+func (m *Mutex) beginAcquire() error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.closing.Load() {
+		return ErrClosing
+	}
+	m.acquiring++
+	return nil
+}
+
+// endAcquireLocked ends an acquisition counted by beginAcquire. Must be called with m.m held.
+func (m *Mutex) endAcquireLocked() {
+	m.acquiring--
+	m.settle()
+}
+
+// idle reports whether the Mutex neither holds nor acquires a lock. Must be called with m.m held.
+func (m *Mutex) idle() bool {
+	return !m.held && m.shared == 0 && m.acquiring == 0
+}
+
+// settle wakes DrainAndClose once the Mutex is idle. Must be called with m.m held.
+func (m *Mutex) settle() {
+	if m.drained != nil && m.idle() {
+		close(m.drained)
+		m.drained = nil
+	}
+}
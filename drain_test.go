@@ -0,0 +1,64 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDrainAndCloseWaitsForHolder(t *testing.T) {
+	db := newFakeDB()
+	m, err := NewMutex(WithConn(db.session()), WithResourceID(163))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	queued := make(chan error, 1)
+	go func() { queued <- m.Lock() }()
+
+	done := make(chan error, 1)
+	go func() { done <- m.DrainAndClose(context.Background()) }()
+	for !m.closing.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("DrainAndClose returned %v while the lock was held", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	// The attempt queued behind the holder is rejected rather than taking the lock
+	if err := <-queued; !errors.Is(err, ErrClosing) {
+		t.Fatalf("queued Lock = %v, want ErrClosing", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("DrainAndClose = %v", err)
+	}
+}
+
+func TestDrainAndCloseReleasesOnTimeout(t *testing.T) {
+	db := newFakeDB()
+	m, err := NewMutex(WithConn(db.session()), WithResourceID(163))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.DrainAndClose(ctx); err != nil {
+		t.Fatalf("DrainAndClose = %v", err)
+	}
+	other := newTestMutex(t, WithConn(db.session()), WithResourceID(163))
+	if ok, err := other.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock after DrainAndClose = %t, %v, want the lock released", ok, err)
+	}
+}
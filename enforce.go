@@ -0,0 +1,44 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// checkEnforcement verifies that a lock held by the Mutex session cannot be taken by
+// a second session, dialed from WithConnStr or given by WithProbeConn. A fresh key is
+// used so that locks of the resource are not disturbed.
+func (m *Mutex) checkEnforcement(ctx context.Context) error {
+	other := m.probe
+	if other == nil {
+		if m.connStr == "" {
+			return fmt.Errorf("enforcement check requires a connection string or a probe connection")
+		}
+		c, err := m.dial(ctx)
+		if err != nil {
+			return err
+		}
+		defer c.Close(context.Background())
+		other = c
+	}
+
+	key := time.Now().UnixNano()
+	var acquired bool
+	if err := m.queryRow(ctx, sqlTryLock, key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to check advisory lock enforcement: %w", err)
+	}
+	if !acquired {
+		return fmt.Errorf("%w: a fresh lock could not be taken", ErrLocksNotEnforced)
+	}
+	defer func() { _, _ = m.exec(context.Background(), sqlUnlock, key) }()
+
+	if err := other.QueryRow(ctx, sqlTryLock, key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to check advisory lock enforcement: %w", err)
+	}
+	if acquired {
+		_, _ = other.Exec(context.Background(), sqlUnlock, key)
+		return fmt.Errorf("%w: a second session took a held lock", ErrLocksNotEnforced)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package pgxmutex
+
+import "errors"
+
+// ErrLockNotAcquired is returned when the lock could not be acquired before giving up.
+var ErrLockNotAcquired = errors.New("lock not acquired")
+
+// ErrLockTimeout is returned when the lock could not be acquired within the configured time budget.
+var ErrLockTimeout = errors.New("lock timeout")
+
+// ErrGateClosed is returned by Gate.Enter once the gate has been closed.
+var ErrGateClosed = errors.New("gate closed")
+
+// ErrLockNotHeld is returned when releasing a lock that this Mutex does not hold.
+var ErrLockNotHeld = errors.New("lock not held")
+
+// ErrLockLost is returned when a lock this Mutex believed it held is no longer held by the session.
+var ErrLockLost = errors.New("lock lost")
+
+// ErrAlreadyHeldLocally is returned by LockOnce when another holder in this process
+// already holds or is acquiring the lock.
+var ErrAlreadyHeldLocally = errors.New("lock already held locally")
+
+// ErrConnectionLost is returned when the session was lost, releasing any lock it held.
+var ErrConnectionLost = errors.New("connection lost")
+
+// ErrLockHeld is returned by operations that require the lock not to be held by this Mutex.
+var ErrLockHeld = errors.New("lock is held")
+
+// ErrClosing is returned for lock attempts on a Mutex that is being closed by DrainAndClose.
+var ErrClosing = errors.New("mutex is closing")
+
+// ErrLocksNotEnforced is returned by WithEnforcementCheck when the database accepts
+// advisory lock calls without enforcing mutual exclusion.
+var ErrLocksNotEnforced = errors.New("advisory locks are not enforced")
+
+// ErrStaleToken is returned by UnlockWithToken for a token of an earlier acquisition.
+var ErrStaleToken = errors.New("stale lock token")
+
+// ErrCircuitOpen is returned for acquisitions rejected by an open WithCircuitBreaker.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// errRateLimited wraps the error of a WithRateLimit wait that failed.
+var errRateLimited = errors.New("lock rate limit exceeded")
@@ -0,0 +1,167 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// LockerFactory creates Mutexes for different resources that share one connection and
+// configuration. Mutexes of the same resource ID still coordinate within the process, but
+// the shared connection must not be used by several Mutexes concurrently.
+type LockerFactory struct {
+	conn     Conn
+	ownsConn bool
+	ctx      context.Context
+	options  []Option
+}
+
+// NewLockerFactory validates options and returns a factory creating Mutexes on conn with
+// them. If conn is nil, options must include WithConnStr; the factory then dials one
+// connection shared by its Mutexes and closes it in Close. WithLockPool and the pooled
+// connection strategies are rejected.
+func NewLockerFactory(conn Conn, options ...Option) (*LockerFactory, error) {
+	m := &Mutex{ctx: context.Background(), stmts: defaultStatements}
+	for _, opt := range options {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// Options only record their configuration, so validating them opens nothing. Pools
+	// would be created per Mutex, defeating the shared connection.
+	if m.poolCfg != nil || m.poolStr != "" {
+		return nil, fmt.Errorf("lock pools and pooled connection strategies cannot be used with a locker factory")
+	}
+
+	f := &LockerFactory{conn: conn, ctx: m.ctx, options: options}
+	if conn == nil {
+		if m.connStr == "" {
+			return nil, fmt.Errorf("database connection must be provided")
+		}
+		c, err := m.dial(m.ctx)
+		if err != nil {
+			return nil, err
+		}
+		f.conn, f.ownsConn = c, true
+	}
+	return f, nil
+}
+
+// Mutex creates a Mutex for the resource id on the shared connection.
+func (f *LockerFactory) Mutex(id int64) (*Mutex, error) {
+	options := append(append([]Option(nil), f.options...), WithConn(f.conn), WithResourceID(id))
+	return NewMutex(options...)
+}
+
+// Close closes the shared connection if the factory dialed it. Locks still held by its
+// Mutexes are released with the session.
+func (f *LockerFactory) Close() error {
+	if !f.ownsConn {
+		return nil
+	}
+	if c, ok := f.conn.(interface{ Close(context.Context) error }); ok {
+		if err := c.Close(f.ctx); err != nil {
+			return fmt.Errorf("failed to close connection: %w", err)
+		}
+	}
+	return nil
+}
+
+// TryLockAll tries without blocking to take the exclusive locks of all ids, on the
+// shared connection, or none of them. If any lock is taken by another holder or fails,
+// the locks already taken are released again in reverse order and acquired is false.
+// On success the returned function releases all locks once; otherwise it does nothing,
+// so that it can always be deferred. Duplicate ids are locked once.
+func (f *LockerFactory) TryLockAll(ctx context.Context, ids []int64) (acquired bool, release func() error, err error) {
+	var held []*Mutex
+	rollback := func() error {
+		var errs []error
+		for i := len(held) - 1; i >= 0; i-- {
+			errs = append(errs, held[i].UnlockContext(held[i].context()))
+		}
+		return errors.Join(errs...)
+	}
+
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		m, err := f.Mutex(id)
+		if err == nil {
+			acquired, err = m.TryLockContext(ctx)
+		}
+		if err != nil || !acquired {
+			if rerr := rollback(); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release acquired locks: %w", rerr))
+			}
+			return false, noRelease, err
+		}
+		held = append(held, m)
+	}
+
+	var once sync.Once
+	var rerr error
+	return true, func() error {
+		once.Do(func() { rerr = rollback() })
+		return rerr
+	}, nil
+}
+
+// LockReadWrite takes the lock of readID shared and the lock of writeID exclusive on the
+// shared connection, blocking until both are held. The locks are always taken in
+// ascending key order, whatever their mode, so that callers locking overlapping keys
+// cannot deadlock each other. If the second lock fails, the first is released again.
+// The returned function releases both locks once, in reverse order.
+func (f *LockerFactory) LockReadWrite(ctx context.Context, readID, writeID int64) (release func() error, err error) {
+	if readID == writeID {
+		return nil, fmt.Errorf("read and write resource IDs must differ")
+	}
+	read, err := f.Mutex(readID)
+	if err != nil {
+		return nil, err
+	}
+	write, err := f.Mutex(writeID)
+	if err != nil {
+		return nil, err
+	}
+
+	type step struct {
+		lock   func() error
+		unlock func() error
+	}
+	steps := []step{
+		{
+			lock:   func() error { return read.LockWith(ctx, CallMode(ModeShared)) },
+			unlock: func() error { return read.UnlockContext(read.context()) },
+		},
+		{
+			lock:   func() error { return write.LockWith(ctx, CallMode(ModeExclusive)) },
+			unlock: func() error { return write.UnlockContext(write.context()) },
+		},
+	}
+	if writeID < readID {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+
+	if err := steps[0].lock(); err != nil {
+		return nil, err
+	}
+	if err := steps[1].lock(); err != nil {
+		if uerr := steps[0].unlock(); uerr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release acquired lock: %w", uerr))
+		}
+		return nil, err
+	}
+
+	var once sync.Once
+	var rerr error
+	return func() error {
+		once.Do(func() { rerr = errors.Join(steps[1].unlock(), steps[0].unlock()) })
+		return rerr
+	}, nil
+}
@@ -0,0 +1,194 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLockerFactoryRejectsPools(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"lock pool":   WithLockPool(1, 2, "postgres://localhost/none"),
+		"pinned pool": WithConnectionStrategy(StrategyPinnedPool, "postgres://localhost/none"),
+		"per-op pool": WithConnectionStrategy(StrategyPerOpPool, "postgres://localhost/none"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewLockerFactory(nil, opt); err == nil {
+				t.Fatal("NewLockerFactory succeeded, want pool option rejected")
+			}
+		})
+	}
+}
+
+// assertFree fails unless another session of db can take the exclusive locks of ids.
+func assertFree(t *testing.T, db *fakeDB, ids ...int64) {
+	t.Helper()
+	observer := db.session()
+	defer observer.Close(context.Background())
+	for _, id := range ids {
+		var acquired bool
+		if err := observer.QueryRow(context.Background(), sqlTryLock, id).Scan(&acquired); err != nil {
+			t.Fatal(err)
+		}
+		if !acquired {
+			t.Errorf("lock %d still held", id)
+		}
+	}
+}
+
+func TestTryLockAllRollsBackWhenBusy(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 3)
+	s := db.session()
+	f, err := NewLockerFactory(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired, release, err := f.TryLockAll(context.Background(), []int64{1, 2, 3, 4})
+	if err != nil || acquired {
+		t.Fatalf("TryLockAll = %t, %v, want not acquired", acquired, err)
+	}
+	if err := release(); err != nil {
+		t.Errorf("release after a failed TryLockAll: %v", err)
+	}
+	if n := s.count("pg_try_advisory_lock("); n != 3 {
+		t.Errorf("%d locks tried, want 3 stopping at the busy one", n)
+	}
+	assertFree(t, db, 1, 2, 4)
+}
+
+func TestTryLockAllRollsBackOnError(t *testing.T) {
+	db := newFakeDB()
+	s := db.session()
+	f, err := NewLockerFactory(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The third attempt fails
+	boom := errors.New("boom")
+	tries := 0
+	s.hook = func(sql string) {
+		if strings.Contains(sql, "pg_try_advisory_lock(") {
+			if tries++; tries == 3 {
+				s.fail = map[string]error{"pg_try_advisory_lock(": boom}
+			}
+		}
+	}
+
+	acquired, _, err := f.TryLockAll(context.Background(), []int64{1, 2, 3})
+	if !errors.Is(err, boom) || acquired {
+		t.Fatalf("TryLockAll = %t, %v, want %v", acquired, err, boom)
+	}
+	s.hook, s.fail = nil, nil
+	assertFree(t, db, 1, 2, 3)
+}
+
+func TestTryLockAllReleasesOnce(t *testing.T) {
+	db := newFakeDB()
+	s := db.session()
+	f, err := NewLockerFactory(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired, release, err := f.TryLockAll(context.Background(), []int64{1, 2, 2})
+	if err != nil || !acquired {
+		t.Fatalf("TryLockAll = %t, %v, want acquired", acquired, err)
+	}
+	if n := s.count("pg_try_advisory_lock("); n != 2 {
+		t.Errorf("%d locks tried, want duplicates locked once", n)
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := release(); err != nil {
+		t.Errorf("second release: %v", err)
+	}
+	if n := s.count("pg_advisory_unlock("); n != 2 {
+		t.Errorf("%d unlocks, want 2", n)
+	}
+	assertFree(t, db, 1, 2)
+}
+
+func TestLockReadWriteKeyOrder(t *testing.T) {
+	for name, ids := range map[string][2]int64{"read first": {1, 2}, "write first": {2, 1}} {
+		t.Run(name, func(t *testing.T) {
+			db := newFakeDB()
+			s := db.session()
+			f, err := NewLockerFactory(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			release, err := f.LockReadWrite(context.Background(), ids[0], ids[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var locks []string
+			for _, sql := range s.statements() {
+				if strings.Contains(sql, "pg_advisory_lock") {
+					locks = append(locks, sql)
+				}
+			}
+			wantSharedFirst := ids[0] < ids[1]
+			if len(locks) != 2 || strings.Contains(locks[0], "_shared") != wantSharedFirst {
+				t.Errorf("locks taken as %q, want ascending key order", locks)
+			}
+			if err := release(); err != nil {
+				t.Fatal(err)
+			}
+			assertFree(t, db, 1, 2)
+		})
+	}
+}
+
+func TestLockReadWriteRollsBackFirstLock(t *testing.T) {
+	for name, ids := range map[string][2]int64{"shared first": {1, 2}, "exclusive first": {2, 1}} {
+		t.Run(name, func(t *testing.T) {
+			db := newFakeDB()
+			// The lock with the higher key, taken second, is busy
+			holdFake(t, db, 2)
+			f, err := NewLockerFactory(db.session())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err := f.LockReadWrite(ctx, ids[0], ids[1]); err == nil {
+				t.Fatal("LockReadWrite succeeded while a lock was busy")
+			}
+			assertFree(t, db, 1)
+		})
+	}
+}
+
+func TestTryClaimLoserRelease(t *testing.T) {
+	db := newFakeDB()
+	winner, err := NewLockerFactory(db.session())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loser, err := NewLockerFactory(db.session())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	won, release, err := winner.TryClaim(context.Background(), "msg-175")
+	if err != nil || !won {
+		t.Fatalf("TryClaim = %t, %v, want won", won, err)
+	}
+	defer release()
+
+	won, lost, err := loser.TryClaim(context.Background(), "msg-175")
+	if err != nil || won {
+		t.Fatalf("second TryClaim = %t, %v, want lost", won, err)
+	}
+	if err := lost(); err != nil {
+		t.Errorf("release of a lost claim: %v", err)
+	}
+}
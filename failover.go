@@ -0,0 +1,32 @@
+package pgxmutex
+
+import "errors"
+
+// withFailover runs attempt, moving to the next WithConnections candidate and retrying
+// while attempt fails because the connection is lost. Each candidate is tried at most once.
+func (m *Mutex) withFailover(attempt func() error) error {
+	for tries := 1; ; tries++ {
+		err := attempt()
+		if err == nil || tries >= len(m.conns) || !m.failover(err) {
+			return err
+		}
+	}
+}
+
+// failover switches to the next candidate connection after err if the connection was
+// lost and the Mutex holds no lock on it. Reports whether it switched.
+func (m *Mutex) failover(err error) bool {
+	if !errors.Is(m.classifyError(err), ErrConnectionLost) {
+		return false
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.held || m.shared > 0 {
+		return false
+	}
+	m.connIdx = (m.connIdx + 1) % len(m.conns)
+	m.conn = m.conns[m.connIdx]
+	return true
+}
@@ -0,0 +1,441 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB is an in-memory stand-in for the advisory lock functions of a PostgreSQL server,
+// so that tests run without a database. Each fakeSession is one backend. It understands
+// the statements the package runs on the lock connection; others fail.
+type fakeDB struct {
+	mu      sync.Mutex
+	changed chan struct{}
+	nextPID uint32
+	locks   map[fakeKey]*fakeLock
+
+	// inRecovery is reported by pg_is_in_recovery.
+	inRecovery bool
+}
+
+// fakeRoundTrip is the latency of the replies of a fakeDB that are sent asynchronously.
+const fakeRoundTrip = 5 * time.Millisecond
+
+// fakeXact marks the holder of transaction-level holds of a session in fakeLock.
+const fakeXact = 1 << 31
+
+// fakeKey is an advisory lock key as shown in pg_locks.
+type fakeKey struct {
+	classid, objid uint32
+	objsubid       int
+}
+
+// fakeLock counts the holds of each session on one key.
+type fakeLock struct {
+	exclusive map[uint32]int
+	shared    map[uint32]int
+	waiting   int
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{changed: make(chan struct{}), locks: make(map[fakeKey]*fakeLock)}
+}
+
+// session opens a new backend.
+func (db *fakeDB) session() *fakeSession {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.nextPID++
+	return &fakeSession{db: db, pid: db.nextPID, settings: map[string]string{"lock_timeout": "0", "application_name": ""}}
+}
+
+// lock returns the lock of key, creating it. Must be called with db.mu held.
+func (db *fakeDB) lock(key fakeKey) *fakeLock {
+	l, ok := db.locks[key]
+	if !ok {
+		l = &fakeLock{exclusive: make(map[uint32]int), shared: make(map[uint32]int)}
+		db.locks[key] = l
+	}
+	return l
+}
+
+// broadcast wakes up waiting sessions. Must be called with db.mu held.
+func (db *fakeDB) broadcast() {
+	close(db.changed)
+	db.changed = make(chan struct{})
+}
+
+// grantable reports whether pid may take l in the given mode. Holds of the session,
+// session- or transaction-level, do not conflict. Must be called with db.mu held.
+func (l *fakeLock) grantable(pid uint32, shared bool) bool {
+	for p, n := range l.exclusive {
+		if p&^fakeXact != pid && n > 0 {
+			return false
+		}
+	}
+	if shared {
+		return true
+	}
+	for p, n := range l.shared {
+		if p&^fakeXact != pid && n > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fakeSession is one backend of a fakeDB. It implements Conn.
+type fakeSession struct {
+	db       *fakeDB
+	pid      uint32
+	settings map[string]string
+	closed   bool
+
+	// fail, if set, makes statements containing its key fail with its error.
+	fail map[string]error
+	// rows, if set, answers the statements it contains with the given values.
+	rows map[string][]interface{}
+	// unlockFalse makes unlock functions report false.
+	unlockFalse bool
+	// pidDrift makes each pg_backend_pid call report another PID.
+	pidDrift bool
+	// closeOnCancel closes the session when ctx is done while a statement waits, like pgx
+	// does when it cancels a running statement.
+	closeOnCancel bool
+	// hook, if set, is called with each statement before it runs.
+	hook func(sql string)
+
+	stmts []string
+}
+
+var fakeFuncRe = regexp.MustCompile(`pg_(try_)?advisory_(xact_)?(lock|unlock)(_shared|_all)?"?\(`)
+
+func (s *fakeSession) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	_, err := s.query(ctx, sql, arguments)
+	return pgconn.CommandTag{}, err
+}
+
+func (s *fakeSession) QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row {
+	vals, err := s.query(ctx, sql, optionsAndArgs)
+	return fakeRow{vals: vals, err: err}
+}
+
+// Close ends the session, releasing its locks.
+func (s *fakeSession) Close(ctx context.Context) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.closed = true
+	s.releaseAll(true)
+	return nil
+}
+
+// releaseAll drops the holds of the session, of all kinds or only transaction-level ones.
+// Must be called with db.mu held.
+func (s *fakeSession) releaseAll(all bool) {
+	for key, l := range s.db.locks {
+		if all {
+			delete(l.exclusive, s.pid)
+			delete(l.shared, s.pid)
+		}
+		delete(l.exclusive, s.pid|fakeXact)
+		delete(l.shared, s.pid|fakeXact)
+		if len(l.exclusive) == 0 && len(l.shared) == 0 && l.waiting == 0 {
+			delete(s.db.locks, key)
+		}
+	}
+	s.db.broadcast()
+}
+
+// statements returns the statements run on the session so far.
+func (s *fakeSession) statements() []string {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return append([]string(nil), s.stmts...)
+}
+
+// count returns the number of statements run on the session so far that contain part.
+func (s *fakeSession) count(part string) int {
+	n := 0
+	for _, sql := range s.statements() {
+		if strings.Contains(sql, part) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *fakeSession) query(ctx context.Context, sql string, args []interface{}) ([]interface{}, error) {
+	if len(args) > 0 {
+		if _, ok := args[0].(pgx.QueryExecMode); ok {
+			args = args[1:]
+		}
+	}
+	if s.hook != nil {
+		s.hook(sql)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.stmts = append(s.stmts, sql)
+	if s.closed {
+		return nil, fmt.Errorf("conn closed")
+	}
+	for part, err := range s.fail {
+		if strings.Contains(sql, part) {
+			return nil, err
+		}
+	}
+
+	if vals, ok := s.rows[sql]; ok {
+		return vals, nil
+	}
+
+	switch {
+	case strings.Contains(sql, "FROM pg_locks"):
+		return s.queryLocks(sql, args)
+	case fakeFuncRe.MatchString(sql):
+		return s.callLockFunc(ctx, sql, args)
+	case sql == "SELECT 1":
+		return []interface{}{1}, nil
+	case sql == "SELECT pg_backend_pid()":
+		if s.pidDrift {
+			s.db.nextPID++
+			return []interface{}{s.db.nextPID}, nil
+		}
+		return []interface{}{s.pid}, nil
+	case sql == "SELECT pg_is_in_recovery()":
+		return []interface{}{s.db.inRecovery}, nil
+	case strings.HasPrefix(sql, "SELECT current_setting('"):
+		name := sql[len("SELECT current_setting('"):]
+		name = name[:strings.IndexByte(name, '\'')]
+		prev := s.settings[name]
+		if strings.Contains(sql, "set_config(") {
+			s.settings[name] = fmt.Sprint(args[0])
+			return []interface{}{prev, s.settings[name]}, nil
+		}
+		return []interface{}{prev}, nil
+	case strings.HasPrefix(sql, "SELECT set_config('"):
+		name := sql[len("SELECT set_config('"):]
+		name = name[:strings.IndexByte(name, '\'')]
+		s.settings[name] = fmt.Sprint(args[0])
+		return []interface{}{s.settings[name]}, nil
+	case sql == "COMMIT", sql == "ROLLBACK":
+		s.releaseAll(false)
+		return nil, nil
+	case strings.HasPrefix(sql, "SAVEPOINT "), strings.HasPrefix(sql, "RELEASE SAVEPOINT "), strings.HasPrefix(sql, "ROLLBACK TO SAVEPOINT "):
+		return nil, nil
+	case strings.HasPrefix(sql, "INSERT INTO "), strings.HasPrefix(sql, "DELETE FROM "):
+		// Table statements, such as those of the lease table, are accepted and ignored
+		return nil, nil
+	}
+	return nil, fmt.Errorf("fakedb: unsupported statement %q", sql)
+}
+
+// key returns the pg_locks key of the arguments of a lock function.
+func fakeLockKey(args []interface{}) (fakeKey, error) {
+	switch len(args) {
+	case 1:
+		id, err := fakeInt(args[0])
+		if err != nil {
+			return fakeKey{}, err
+		}
+		return fakeKey{classid: uint32(uint64(id) >> 32), objid: uint32(id), objsubid: 1}, nil
+	case 2:
+		a, err := fakeInt(args[0])
+		if err != nil {
+			return fakeKey{}, err
+		}
+		b, err := fakeInt(args[1])
+		if err != nil {
+			return fakeKey{}, err
+		}
+		return fakeKey{classid: uint32(a), objid: uint32(b), objsubid: 2}, nil
+	}
+	return fakeKey{}, fmt.Errorf("fakedb: unsupported lock arguments %v", args)
+}
+
+func fakeInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("fakedb: unsupported lock argument %T", v)
+}
+
+// callLockFunc runs an advisory lock function. Must be called with db.mu held.
+func (s *fakeSession) callLockFunc(ctx context.Context, sql string, args []interface{}) ([]interface{}, error) {
+	match := fakeFuncRe.FindStringSubmatch(sql)
+	try, xact, op, suffix := match[1] != "", match[2] != "", match[3], match[4]
+	if suffix == "_all" {
+		for _, l := range s.db.locks {
+			delete(l.exclusive, s.pid)
+			delete(l.shared, s.pid)
+		}
+		s.db.broadcast()
+		return []interface{}{nil}, nil
+	}
+
+	key, err := fakeLockKey(args)
+	if err != nil {
+		return nil, err
+	}
+	shared := suffix == "_shared"
+	// Transaction-level holds are kept under a separate holder so that COMMIT drops them
+	holder := s.pid
+	if xact {
+		holder |= fakeXact
+	}
+	l := s.db.lock(key)
+	holds := l.exclusive
+	if shared {
+		holds = l.shared
+	}
+
+	if op == "unlock" {
+		if s.unlockFalse || holds[holder] == 0 {
+			return []interface{}{false}, nil
+		}
+		holds[holder]--
+		if holds[holder] == 0 {
+			delete(holds, holder)
+		}
+		s.db.broadcast()
+		return []interface{}{true}, nil
+	}
+
+	if try {
+		if !l.grantable(s.pid, shared) {
+			return []interface{}{false}, nil
+		}
+		holds[holder]++
+		return []interface{}{true}, nil
+	}
+
+	var timeout <-chan time.Time
+	if d, err := time.ParseDuration(s.settings["lock_timeout"]); err == nil && d > 0 {
+		// The lock timeout error reaches the client a round trip after it fired
+		t := time.NewTimer(d + fakeRoundTrip)
+		defer t.Stop()
+		timeout = t.C
+	}
+	l.waiting++
+	defer func() { l.waiting-- }()
+	for !l.grantable(s.pid, shared) {
+		changed := s.db.changed
+		s.db.mu.Unlock()
+		select {
+		case <-changed:
+			s.db.mu.Lock()
+		case <-timeout:
+			s.db.mu.Lock()
+			return nil, &pgconn.PgError{Code: sqlStateLockNotAvailable, Message: "canceling statement due to lock timeout"}
+		case <-ctx.Done():
+			s.db.mu.Lock()
+			if s.closeOnCancel {
+				s.closed = true
+				s.releaseAll(true)
+			}
+			return nil, ctx.Err()
+		}
+		if s.closed {
+			return nil, fmt.Errorf("conn closed")
+		}
+	}
+	holds[holder]++
+	return []interface{}{nil}, nil
+}
+
+var fakeSubidRe = regexp.MustCompile(`objsubid = (\d)`)
+
+// queryLocks answers the pg_locks queries of the package. Must be called with db.mu held.
+func (s *fakeSession) queryLocks(sql string, args []interface{}) ([]interface{}, error) {
+	if strings.HasPrefix(sql, "SELECT count(*)") && strings.Contains(sql, "pid = pg_backend_pid()") {
+		n := 0
+		for _, l := range s.db.locks {
+			n += l.exclusive[s.pid] + l.shared[s.pid]
+		}
+		return []interface{}{int64(n)}, nil
+	}
+
+	subid := 1
+	if match := fakeSubidRe.FindStringSubmatch(sql); match != nil {
+		subid, _ = strconv.Atoi(match[1])
+	}
+	if len(args) < 2 {
+		return nil, fmt.Errorf("fakedb: unsupported pg_locks arguments %v", args)
+	}
+	classid, _ := fakeInt(args[0])
+	objid, _ := fakeInt(args[1])
+	l := s.db.locks[fakeKey{classid: uint32(classid), objid: uint32(objid), objsubid: subid}]
+	if l == nil {
+		l = &fakeLock{}
+	}
+
+	switch {
+	case strings.Contains(sql, "NOT granted"):
+		return []interface{}{int64(l.waiting)}, nil
+	case strings.HasPrefix(sql, "SELECT NOT EXISTS"):
+		return []interface{}{len(l.exclusive) == 0 && len(l.shared) == 0}, nil
+	case strings.Contains(sql, "pid = pg_backend_pid()"):
+		return []interface{}{l.exclusive[s.pid] > 0 || l.shared[s.pid] > 0}, nil
+	case strings.Contains(sql, "pid = $3"):
+		pid, _ := fakeInt(args[2])
+		return []interface{}{l.exclusive[uint32(pid)] > 0 || l.shared[uint32(pid)] > 0}, nil
+	}
+	return nil, fmt.Errorf("fakedb: unsupported statement %q", sql)
+}
+
+// fakeRow is the result of fakeSession.QueryRow.
+type fakeRow struct {
+	vals []interface{}
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(dest) != len(r.vals) {
+		return fmt.Errorf("fakedb: scanning %d values into %d destinations", len(r.vals), len(dest))
+	}
+	for i, d := range dest {
+		v := r.vals[i]
+		switch d := d.(type) {
+		case *bool:
+			*d = v.(bool)
+		case *string:
+			*d = v.(string)
+		case *int:
+			n, _ := fakeInt(v)
+			if u, ok := v.(uint32); ok {
+				n = int64(u)
+			}
+			*d = int(n)
+		case *int64:
+			n, _ := fakeInt(v)
+			if u, ok := v.(uint32); ok {
+				n = int64(u)
+			}
+			*d = n
+		case *uint32:
+			*d = v.(uint32)
+		case *interface{}:
+			*d = v
+		default:
+			return fmt.Errorf("fakedb: unsupported destination %T", d)
+		}
+	}
+	return nil
+}
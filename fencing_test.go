@@ -0,0 +1,77 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnlockWithToken(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithResourceID(139))
+
+	stale, err := m.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnlockWithToken(ctx, stale); err != nil {
+		t.Fatalf("UnlockWithToken of the current acquisition = %v", err)
+	}
+
+	current, err := m.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnlockWithToken(ctx, stale); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("UnlockWithToken of an earlier acquisition = %v, want ErrStaleToken", err)
+	}
+	if err := m.UnlockWithToken(ctx, Token{}); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("UnlockWithToken of the zero token = %v, want ErrStaleToken", err)
+	}
+	if held, err := m.IsHeld(ctx); err != nil || !held {
+		t.Fatalf("lock released by a rejected token: %t, %v", held, err)
+	}
+	if err := m.UnlockWithToken(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnlockWithToken(ctx, current); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("second UnlockWithToken = %v, want ErrStaleToken", err)
+	}
+}
+
+func TestUnlockWithTokenRejectsSharedHolds(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithResourceID(139), WithLockMode(ModeShared))
+
+	tok, err := m.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UnlockWithToken(ctx, tok); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("UnlockWithToken of a shared hold = %v, want ErrStaleToken", err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireReleasesAfterFenceFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newFakeDB().session()
+	s.fail = map[string]error{"INSERT INTO": errors.New("relation \"fences\" does not exist")}
+	m := newTestMutex(t, WithConn(s), WithResourceID(139), WithFencingTable("fences"))
+	// The fence fails because the caller gave up; the lock must still be released
+	s.hook = func(sql string) {
+		if strings.HasPrefix(sql, "INSERT INTO") {
+			cancel()
+		}
+	}
+
+	if _, err := m.Acquire(ctx); err == nil || !strings.Contains(err.Error(), "fencing token") {
+		t.Fatalf("Acquire = %v, want the fencing error", err)
+	}
+	if held, err := m.IsHeld(context.Background()); err != nil || held {
+		t.Fatalf("lock held after the fence failed: %t, %v", held, err)
+	}
+}
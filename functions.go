@@ -0,0 +1,138 @@
+package pgxmutex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statements are the lock statements of a Mutex, each taking the arguments prepared by
+// buildKeyArgs. Empty unlock statements release the lock locally only.
+type statements struct {
+	lock, tryLock, unlock                   string
+	lockShared, tryLockShared, unlockShared string
+}
+
+var defaultStatements = &statements{
+	lock:          sqlLock,
+	tryLock:       sqlTryLock,
+	unlock:        sqlUnlock,
+	lockShared:    sqlLockShared,
+	tryLockShared: sqlTryLockShared,
+	unlockShared:  sqlUnlockShared,
+}
+
+// xactStatements take transaction-level locks, which have no unlock function: they are
+// released when the transaction ends.
+var xactStatements = &statements{
+	lock:          sqlXactLock,
+	tryLock:       sqlTryXactLock,
+	lockShared:    sqlXactLockShared,
+	tryLockShared: sqlTryXactLockShared,
+}
+
+// LockFunctions names SQL functions replacing the built-in advisory lock functions, such
+// as security definer wrappers. Names may be schema-qualified. Empty names keep the
+// built-in function. The try and unlock functions must return boolean.
+type LockFunctions struct {
+	Lock          string `json:"lock,omitempty"`
+	TryLock       string `json:"try_lock,omitempty"`
+	Unlock        string `json:"unlock,omitempty"`
+	LockShared    string `json:"lock_shared,omitempty"`
+	TryLockShared string `json:"try_lock_shared,omitempty"`
+	UnlockShared  string `json:"unlock_shared,omitempty"`
+
+	// Args are fixed extra arguments passed to every function, after the lock key or,
+	// with ArgsFirst, before it. When Args are set, all six functions must be named since
+	// the built-in functions take the key only.
+	Args      []interface{} `json:"args,omitempty"`
+	ArgsFirst bool          `json:"args_first,omitempty"`
+}
+
+// statements validates fns and builds the statements calling them.
+func (fns LockFunctions) statements() (*statements, error) {
+	names := []*string{&fns.Lock, &fns.TryLock, &fns.Unlock, &fns.LockShared, &fns.TryLockShared, &fns.UnlockShared}
+	defaults := []string{"pg_advisory_lock", "pg_try_advisory_lock", "pg_advisory_unlock", "pg_advisory_lock_shared", "pg_try_advisory_lock_shared", "pg_advisory_unlock_shared"}
+
+	placeholders := make([]string, len(fns.Args)+1)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	params := strings.Join(placeholders, ", ")
+
+	calls := make([]string, len(names))
+	for i, name := range names {
+		if *name == "" {
+			if len(fns.Args) > 0 {
+				return nil, fmt.Errorf("all lock functions must be named when extra arguments are used")
+			}
+			*name = defaults[i]
+		}
+		if !validFunctionName(*name) {
+			return nil, fmt.Errorf("invalid lock function name %q", *name)
+		}
+		calls[i] = fmt.Sprintf("SELECT %s(%s)", quoteTable(*name), params)
+	}
+
+	return &statements{
+		lock:          calls[0],
+		tryLock:       calls[1],
+		unlock:        calls[2],
+		lockShared:    calls[3],
+		tryLockShared: calls[4],
+		unlockShared:  calls[5],
+	}, nil
+}
+
+// validFunctionName reports whether name is a plain, possibly schema-qualified identifier.
+func validFunctionName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return false
+		}
+		for i, r := range part {
+			switch {
+			case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			case i > 0 && (r >= '0' && r <= '9' || r == '$'):
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// keyPlaceholder marks where SQL templates take the lock statement arguments.
+const keyPlaceholder = "{key}"
+
+// validTemplate reports whether template is usable for op.
+func validTemplate(op Operation, template string) error {
+	switch op {
+	case OpLock, OpTryLock, OpUnlock, OpLockShared, OpTryLockShared, OpUnlockShared:
+	default:
+		return fmt.Errorf("unknown lock operation %q", op)
+	}
+	if !strings.Contains(template, keyPlaceholder) {
+		return fmt.Errorf("sql template for %s must contain the %s placeholder", op, keyPlaceholder)
+	}
+	return nil
+}
+
+// withTemplates returns a copy of s with the statements of the operations in templates
+// replaced, the key placeholder expanded to the parameters of nargs lock arguments.
+func (s *statements) withTemplates(templates map[Operation]string, nargs int) *statements {
+	placeholders := make([]string, nargs)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	params := strings.Join(placeholders, ", ")
+
+	t := *s
+	fields := map[Operation]*string{
+		OpLock: &t.lock, OpTryLock: &t.tryLock, OpUnlock: &t.unlock,
+		OpLockShared: &t.lockShared, OpTryLockShared: &t.tryLockShared, OpUnlockShared: &t.unlockShared,
+	}
+	for op, template := range templates {
+		*fields[op] = strings.ReplaceAll(template, keyPlaceholder, params)
+	}
+	return &t
+}
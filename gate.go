@@ -0,0 +1,100 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Gate is a read-write gate for graceful shutdown built on shared advisory locks.
+// Workers Enter and Leave the gate while it is open, holding the lock in shared mode.
+// CloseGate takes the lock in exclusive mode and thus waits until all workers, in this
+// and in other processes, have left.
+type Gate struct {
+	m *Mutex
+
+	mu     sync.Mutex
+	n      int
+	closed bool
+	idle   chan struct{}
+}
+
+// NewGate initializes a new Gate with provided options.
+func NewGate(options ...Option) (*Gate, error) {
+	m, err := NewMutex(options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Gate{m: m}, nil
+}
+
+// Enter registers a worker with the gate. The first worker of this process takes the
+// shared lock on behalf of all workers of the process. Returns ErrGateClosed once
+// CloseGate has been called.
+func (g *Gate) Enter() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return ErrGateClosed
+	}
+	if g.n == 0 {
+		if err := g.m.LockShared(); err != nil {
+			return err
+		}
+	}
+	g.n++
+	return nil
+}
+
+// Leave unregisters a worker. The last worker of this process releases the shared lock.
+func (g *Gate) Leave() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.n == 0 {
+		return fmt.Errorf("gate was not entered")
+	}
+	if g.n == 1 {
+		if _, err := g.m.UnlockShared(); err != nil {
+			return err
+		}
+		if g.idle != nil {
+			close(g.idle)
+			g.idle = nil
+		}
+	}
+	g.n--
+	return nil
+}
+
+// CloseGate closes the gate for new workers and blocks until all workers have left.
+// On success the gate holds the exclusive lock until Close is called.
+func (g *Gate) CloseGate(ctx context.Context) error {
+	g.mu.Lock()
+	g.closed = true
+	var idle chan struct{}
+	if g.n > 0 {
+		if g.idle == nil {
+			g.idle = make(chan struct{})
+		}
+		idle = g.idle
+	}
+	g.mu.Unlock()
+
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Workers of other sessions are waited for by PostgreSQL.
+	return g.m.lockExclusive(ctx)
+}
+
+// Close releases the locks held by the gate and closes its connection if owned.
+func (g *Gate) Close() error {
+	return g.m.Close()
+}
@@ -2,7 +2,12 @@ module github.com/jokruger/pgx-mutex
 
 go 1.23
 
-require github.com/jackc/pgx/v5 v5.7.1
+require (
+	github.com/jackc/pgx/v5 v5.7.1
+	go.opentelemetry.io/otel v1.31.0
+	go.opentelemetry.io/otel/metric v1.31.0
+	golang.org/x/time v0.7.0
+)
 
 require (
 	github.com/jackc/pgpassfile v1.0.0 // indirect
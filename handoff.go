@@ -0,0 +1,107 @@
+package pgxmutex
+
+import "time"
+
+// Handoff moves the locks held by m to a new Mutex sharing the same connection and
+// configuration. Afterwards only the returned Mutex may release them; Unlock on m
+// returns ErrLockNotHeld. Ownership of the connection moves along with the locks.
+//
+// Session-level advisory locks belong to the connection, not to a goroutine, so the
+// returned Mutex can be passed to and released from another goroutine. The two
+// Mutexes must not use the shared connection concurrently. Close on m then leaves
+// the connection, lock pool and wakeup listener to the returned Mutex.
+func (m *Mutex) Handoff() *Mutex {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	n := m.clone()
+	n.held, n.shared, n.pins, n.gen = m.held, m.shared, m.pins, m.gen
+	n.exclGen = m.exclGen
+	n.heldSince = m.heldSince
+	n.ownsConn = m.ownsConn
+	n.labeled, n.labelPrev = m.labeled, m.labelPrev
+	n.readerSlots, m.readerSlots = m.readerSlots, nil
+
+	m.held, m.shared, m.pins = false, 0, 0
+	m.heldSince = time.Time{}
+	m.ownsConn = false
+	m.labeled, m.labelPrev = false, ""
+	m.handedOff = true
+	m.released()
+	if m.pool != nil && n.pins > 0 {
+		m.conn = nil
+	}
+
+	if n.held || n.shared > 0 {
+		n.startMonitors()
+		n.track()
+		n.startHoldWatchdog()
+	}
+	return n
+}
+
+// clone returns a Mutex with the same configuration as m and no lock state.
+func (m *Mutex) clone() *Mutex {
+	return &Mutex{
+		m:               stateMutex{off: m.m.off},
+		conn:            m.conn,
+		probe:           m.probe,
+		connStr:         m.connStr,
+		ctx:             m.ctx,
+		so:              m.so,
+		conns:           m.conns,
+		connIdx:         m.connIdx,
+		keyArgs:         m.keyArgs,
+		stmts:           m.stmts,
+		scope:           m.scope,
+		fns:             m.fns,
+		fnArgs:          m.fnArgs,
+		argsFirst:       m.argsFirst,
+		mode:            m.mode,
+		classifier:      m.classifier,
+		allowPool:       m.allowPool,
+		noPanic:         m.noPanic,
+		noLocal:         m.noLocal,
+		actor:           m.actor,
+		pool:            m.pool,
+		connectAttempts: m.connectAttempts,
+		connectDelay:    m.connectDelay,
+		deadline:        m.deadline,
+		coalesce:        m.coalesce,
+		backoff:         m.backoff,
+		fencingTable:    m.fencingTable,
+		leaseTable:      m.leaseTable,
+		execMode:        m.execMode,
+		heartbeat:       m.heartbeat,
+		lossInterval:    m.lossInterval,
+		deadlockRetries: m.deadlockRetries,
+		reconnects:      m.reconnects,
+		reacquire:       m.reacquire,
+		appName:         m.appName,
+		statusTTL:       m.statusTTL,
+		quietUnlock:     m.quietUnlock,
+		priority:        m.priority,
+		tracking:        m.tracking,
+		otel:            m.otel,
+		log:             m.log,
+		retryLogging:    m.retryLogging,
+		wakeup:          m.wakeup,
+		traceFn:         m.traceFn,
+		events:          m.events,
+		checkEnforce:    m.checkEnforce,
+		limiter:         m.limiter,
+		breaker:         m.breaker,
+		maxHold:         m.maxHold,
+		onMaxHold:       m.onMaxHold,
+		autoRelease:     m.autoRelease,
+		onLost:          m.onLost,
+		roundTrips:      m.roundTrips,
+		onRoundTrip:     m.onRoundTrip,
+		interceptor:     m.interceptor,
+
+		metrics:       m.metrics,
+		class:         m.class,
+		auditSink:     m.auditSink,
+		auditMetadata: m.auditMetadata,
+	}
+}
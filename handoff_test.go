@@ -0,0 +1,84 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandoffMovesHeldLock(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	s := db.session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(113))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	n := m.Handoff()
+	if err := m.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("Unlock of the old Mutex = %v, want ErrLockNotHeld", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.count("pg_advisory_unlock("); n != 0 {
+		t.Errorf("old Mutex ran %d unlocks, want none", n)
+	}
+
+	// The new Mutex can be released from another goroutine
+	done := make(chan error)
+	go func() {
+		held, err := n.IsHeld(ctx)
+		if err == nil && !held {
+			err = errors.New("lock not held after the handoff")
+		}
+		if err == nil {
+			err = n.Unlock()
+		}
+		done <- err
+	}()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	assertFree(t, db, 113)
+}
+
+func TestHandoffCopiesConnectionConfig(t *testing.T) {
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithConnectRetry(3, time.Millisecond))
+	// As set by WithConnStr, which a fake session cannot be dialed for
+	m.connStr = "postgres://localhost/pgxmutex"
+
+	n := m.Handoff()
+	if n.connStr != m.connStr || n.connectAttempts != 3 || n.connectDelay != time.Millisecond {
+		t.Errorf("handed off Mutex reconnects to %q with %d attempts %v apart, want the configuration of the old one",
+			n.connStr, n.connectAttempts, n.connectDelay)
+	}
+}
+
+func TestHandoffCloseLeavesActorToNewOwner(t *testing.T) {
+	db := newFakeDB()
+	m, err := NewMutex(WithConn(db.session()), WithResourceID(113), WithConnectionActor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	n := m.Handoff()
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.UnlockContext(ctx); err != nil {
+		t.Fatalf("Unlock after closing the old Mutex: %v", err)
+	}
+	if err := n.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertFree(t, db, 113)
+}
@@ -0,0 +1,20 @@
+package pgxmutex
+
+import "time"
+
+// HeldSince returns when the Mutex acquired the lock it currently holds, exclusive or
+// shared, and false if it holds none. This is local bookkeeping, no query is made.
+func (m *Mutex) HeldSince() (time.Time, bool) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.heldSince, m.held || m.shared > 0
+}
+
+// HeldFor returns how long the current lock has been held, or 0 if none is held.
+func (m *Mutex) HeldFor() time.Duration {
+	since, ok := m.HeldSince()
+	if !ok {
+		return 0
+	}
+	return time.Since(since)
+}
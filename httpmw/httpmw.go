@@ -0,0 +1,62 @@
+// Package httpmw provides net/http middleware serializing request handling per resource
+// across a cluster with pgxmutex locks.
+package httpmw
+
+import (
+	"net/http"
+
+	pgxmutex "github.com/jokruger/pgx-mutex"
+)
+
+// Config controls how LockMiddleware responds when the lock is not available.
+type Config struct {
+	// Wait makes the middleware block until the lock is acquired or the request context is
+	// done. By default the lock is only attempted and contention is rejected immediately.
+	Wait bool
+	// ContentionStatus is the response status when the lock is held elsewhere.
+	// Defaults to http.StatusConflict.
+	ContentionStatus int
+	// ErrorStatus is the response status when the lock could not be created or acquired
+	// because of an error. Defaults to http.StatusServiceUnavailable.
+	ErrorStatus int
+}
+
+// LockMiddleware acquires the lock of the resource returned by keyFunc for the duration
+// of the request and releases it when the handler returns. factory creates the Mutex
+// for a resource ID; it is closed after the request, which releases the lock.
+func LockMiddleware(keyFunc func(*http.Request) int64, factory func(int64) (*pgxmutex.Mutex, error), cfg Config) func(http.Handler) http.Handler {
+	if cfg.ContentionStatus == 0 {
+		cfg.ContentionStatus = http.StatusConflict
+	}
+	if cfg.ErrorStatus == 0 {
+		cfg.ErrorStatus = http.StatusServiceUnavailable
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m, err := factory(keyFunc(r))
+			if err != nil {
+				http.Error(w, http.StatusText(cfg.ErrorStatus), cfg.ErrorStatus)
+				return
+			}
+			defer m.Close()
+
+			if cfg.Wait {
+				err = m.LockContext(r.Context())
+			} else {
+				var acquired bool
+				acquired, err = m.TryLockContext(r.Context())
+				if err == nil && !acquired {
+					http.Error(w, http.StatusText(cfg.ContentionStatus), cfg.ContentionStatus)
+					return
+				}
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(cfg.ErrorStatus), cfg.ErrorStatus)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,34 +2,214 @@ package pgxmutex
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-type conn interface {
+const (
+	sqlLock              = "SELECT pg_advisory_lock($1)"
+	sqlTryLock           = "SELECT pg_try_advisory_lock($1)"
+	sqlUnlock            = "SELECT pg_advisory_unlock($1)"
+	sqlLockShared        = "SELECT pg_advisory_lock_shared($1)"
+	sqlTryLockShared     = "SELECT pg_try_advisory_lock_shared($1)"
+	sqlUnlockShared      = "SELECT pg_advisory_unlock_shared($1)"
+	sqlXactLock          = "SELECT pg_advisory_xact_lock($1)"
+	sqlTryXactLock       = "SELECT pg_try_advisory_xact_lock($1)"
+	sqlXactLockShared    = "SELECT pg_advisory_xact_lock_shared($1)"
+	sqlTryXactLockShared = "SELECT pg_try_advisory_xact_lock_shared($1)"
+	sqlUnlockAll         = "SELECT pg_advisory_unlock_all()"
+	sqlSetAppName        = "SELECT set_config('application_name', $1, false)"
+	sqlHeldCount         = "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted"
+
+	// bigint keys are stored in pg_locks as classid (high half), objid (low half) and objsubid 1.
+	// Keys taken by WithLockFunctions are unknown, so these queries are rejected for them.
+	sqlIsHeld    = "SELECT EXISTS (SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND pid = pg_backend_pid() AND granted AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1)"
+	sqlIsHeldBy  = "SELECT EXISTS (SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND pid = $3 AND granted AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1)"
+	sqlAvailable = "SELECT NOT EXISTS (SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND granted AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1)"
+	sqlWaiters   = "SELECT count(*) FROM pg_locks WHERE locktype = 'advisory' AND NOT granted AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1"
+)
+
+// Conn is the connection used by a Mutex, implemented by *pgx.Conn and wrappers such as
+// ConnMiddleware.
+type Conn interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row
 }
 
+// singleton coordinates goroutines of this process that lock the same resource ID.
+// Exclusive holders take the write lock, shared holders take the read lock.
 type singleton struct {
-	sync.Mutex
-	id int64
+	sync.RWMutex
+	id      int64
+	waiting atomic.Int64
+
+	// holders counts the Mutexes holding the lock, guarding ResetSingleton.
+	holders atomic.Int64
+
+	// busyUntil and busySharedUntil hold the UnixNano time until which coalesced
+	// attempts treat the advisory lock as taken by another session.
+	busyUntil       atomic.Int64
+	busySharedUntil atomic.Int64
+
+	// flight and flightShared are the coalesced attempts in flight, guarded by flightMu.
+	flightMu     sync.Mutex
+	flight       *tryFlight
+	flightShared *tryFlight
+
+	// queue orders waiters with WithPriority levels, counted by ranked.
+	queue  priorityQueue
+	ranked atomic.Int64
+
+	// available caches Available results for WithStatusCache.
+	statusMu  sync.Mutex
+	available statusEntry
+}
+
+// lockWaiting takes the write lock, counting the caller as waiting until it succeeds.
+// Waiters with a priority level yield to pending waiters of higher levels.
+func (s *singleton) lockWaiting(level int) {
+	s.waiting.Add(1)
+	s.await(level, s.Lock)
+	s.waiting.Add(-1)
 }
 
-var singletons = make(map[int64]*singleton)
+// rlockWaiting takes the read lock, counting the caller as waiting until it succeeds.
+func (s *singleton) rlockWaiting(level int) {
+	s.waiting.Add(1)
+	s.await(level, s.RLock)
+	s.waiting.Add(-1)
+}
+
+// await runs lock, queued by level while prioritized waiters are around.
+func (s *singleton) await(level int, lock func()) {
+	if level == 0 && s.ranked.Load() == 0 {
+		lock()
+		return
+	}
+	if level != 0 {
+		s.ranked.Add(1)
+		defer s.ranked.Add(-1)
+	}
+	s.queue.wait(level, lock)
+}
+
+// singletonKey is the full identity of a lock in the database: the key and, for lock
+// functions taking extra arguments, the functions and arguments that partition it.
+type singletonKey struct {
+	id        int64
+	namespace string
+}
+
+var singletons = make(map[singletonKey]*singleton)
 var singletonsMutex sync.Mutex
 
-func getSingleton(id int64) *singleton {
+func getSingleton(key singletonKey) *singleton {
 	singletonsMutex.Lock()
 	defer singletonsMutex.Unlock()
 
-	if s, ok := singletons[id]; ok {
+	if s, ok := singletons[key]; ok {
 		return s
 	}
 
-	s := &singleton{id: id}
-	singletons[id] = s
+	s := &singleton{id: key.id}
+	singletons[key] = s
 	return s
 }
+
+// resolveSingleton returns the local coordination entry for id. Without local
+// serialization, the Mutex gets a private entry shared with no other Mutex.
+func (m *Mutex) resolveSingleton(id int64) *singleton {
+	if m.noLocal {
+		return &singleton{id: id}
+	}
+	return getSingleton(singletonKey{id: id, namespace: m.namespace()})
+}
+
+// namespace identifies the lock space of the Mutex beyond the key. The built-in session
+// and transaction-level functions share one space; otherwise Mutexes share a space only
+// if they run the same statements with the same arguments.
+func (m *Mutex) namespace() string {
+	if len(m.fnArgs) == 0 && (m.stmts == defaultStatements || m.stmts == xactStatements) {
+		return ""
+	}
+	return fmt.Sprintf("%q %v %t", *m.stmts, m.fnArgs, m.argsFirst)
+}
+
+// RegistryStats reports the number of resource IDs known to the in-process
+// coordination registry and the number of goroutines currently waiting on a local
+// holder of the same resource ID.
+func RegistryStats() (size int, waiting int) {
+	singletonsMutex.Lock()
+	defer singletonsMutex.Unlock()
+
+	for _, s := range singletons {
+		waiting += int(s.waiting.Load())
+	}
+	return len(singletons), waiting
+}
+
+// hashKey derives a stable advisory lock key from a string using 64-bit FNV-1a.
+func hashKey(s string) int64 {
+	return hashBytes([]byte(s))
+}
+
+// hashBytes derives a stable advisory lock key from bytes using 64-bit FNV-1a.
+func hashBytes(b []byte) int64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return int64(h.Sum64())
+}
+
+// exec runs sql on the Mutex connection, applying the configured query exec mode.
+func (m *Mutex) exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return m.connExec(ctx, sql, m.queryArgs(args))
+}
+
+// queryRow runs sql on the Mutex connection, applying the configured query exec mode.
+func (m *Mutex) queryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return m.connQueryRow(ctx, sql, m.queryArgs(args))
+}
+
+// buildKeyArgs prepares the arguments of the lock statements, the lock key and any extra
+// lock function arguments, so that the hot lock paths do not allocate them on every call.
+// The slice is only read afterwards.
+func (m *Mutex) buildKeyArgs() {
+	args := make([]interface{}, 0, len(m.fnArgs)+1)
+	if m.argsFirst {
+		args = append(append(args, m.fnArgs...), m.so.id)
+	} else {
+		args = append(append(args, m.so.id), m.fnArgs...)
+	}
+	m.keyArgs = m.queryArgs(args)
+}
+
+// execKey runs sql with the lock statement arguments.
+func (m *Mutex) execKey(ctx context.Context, sql string) (pgconn.CommandTag, error) {
+	return m.connExec(ctx, sql, m.keyArgs)
+}
+
+// queryRowKey runs sql with the lock statement arguments.
+func (m *Mutex) queryRowKey(ctx context.Context, sql string) pgx.Row {
+	return m.connQueryRow(ctx, sql, m.keyArgs)
+}
+
+// queryArgs prepends the configured query exec mode to args, if any.
+func (m *Mutex) queryArgs(args []interface{}) []interface{} {
+	if m.execMode == nil {
+		return args
+	}
+	return append([]interface{}{*m.execMode}, args...)
+}
+
+// splitKey decomposes a bigint advisory key into the unsigned classid and objid
+// values shown in pg_locks. The halves are taken from the two's complement bits, so the
+// sign of a negative key ends up in the high bit of classid, e.g. -1 is shown as classid
+// and objid 4294967295.
+func splitKey(id int64) (classid, objid int64) {
+	return int64(uint64(id) >> 32), int64(uint32(id))
+}
@@ -0,0 +1,87 @@
+package pgxmutex
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		id             int64
+		classid, objid int64
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{1 << 32, 1, 0},
+		{-1, 0xffffffff, 0xffffffff},
+		{-2, 0xffffffff, 0xfffffffe},
+		{-1 << 32, 0xffffffff, 0},
+		{math.MaxInt64, 0x7fffffff, 0xffffffff},
+		{math.MinInt64, 0x80000000, 0},
+	}
+	for _, tt := range tests {
+		classid, objid := splitKey(tt.id)
+		if classid != tt.classid || objid != tt.objid {
+			t.Errorf("splitKey(%d) = %d, %d, want %d, %d", tt.id, classid, objid, tt.classid, tt.objid)
+		}
+		// pg_locks shows the halves as unsigned oids, which PackKeys reassembles
+		if id := PackKeys(int32(uint32(classid)), int32(uint32(objid))); id != tt.id {
+			t.Errorf("PackKeys of splitKey(%d) = %d", tt.id, id)
+		}
+	}
+}
+
+// tenantFunctions are the built-in functions called with an extra argument, which makes
+// them the two-int4 variants: a different lock space than the bigint key alone.
+func tenantFunctions(tenant int32) LockFunctions {
+	return LockFunctions{
+		Lock: "pg_advisory_lock", TryLock: "pg_try_advisory_lock", Unlock: "pg_advisory_unlock",
+		LockShared: "pg_advisory_lock_shared", TryLockShared: "pg_try_advisory_lock_shared", UnlockShared: "pg_advisory_unlock_shared",
+		Args: []interface{}{tenant},
+	}
+}
+
+func TestSingletonNamespaces(t *testing.T) {
+	db := newFakeDB()
+	plain := newTestMutex(t, WithConn(db.session()), WithResourceID(161))
+	xact := newTestMutex(t, WithConn(db.session()), WithResourceID(161), WithLockScope(ScopeXact))
+	tenant1 := newTestMutex(t, WithConn(db.session()), WithResourceID(161), WithLockFunctions(tenantFunctions(1)))
+	tenant1b := newTestMutex(t, WithConn(db.session()), WithResourceID(161), WithLockFunctions(tenantFunctions(1)))
+	tenant2 := newTestMutex(t, WithConn(db.session()), WithResourceID(161), WithLockFunctions(tenantFunctions(2)))
+
+	if plain.so != xact.so {
+		t.Error("session and transaction-level locks of one key do not share a singleton")
+	}
+	if tenant1.so != tenant1b.so {
+		t.Error("Mutexes of the same lock identity do not share a singleton")
+	}
+	if plain.so == tenant1.so || tenant1.so == tenant2.so {
+		t.Error("Mutexes of different namespaces share a singleton")
+	}
+
+	// Locks of the same ID in different namespaces do not block each other locally
+	for _, m := range []*Mutex{plain, tenant1, tenant2} {
+		if outcome, err := m.TryLockDetailed(context.Background()); err != nil || outcome != AcquiredLocalAndRemote {
+			t.Fatalf("TryLockDetailed = %v, %v, want acquired", outcome, err)
+		}
+	}
+	if outcome, err := tenant1b.TryLockDetailed(context.Background()); err != nil || outcome != BlockedLocally {
+		t.Fatalf("TryLockDetailed in a held namespace = %v, %v, want blocked locally", outcome, err)
+	}
+}
+
+func TestSingletonNamespacesWithTemplates(t *testing.T) {
+	db := newFakeDB()
+	plain := newTestMutex(t, WithConn(db.session()), WithResourceID(174))
+	unlock := newTestMutex(t, WithConn(db.session()), WithResourceID(174), WithSQLTemplate(OpUnlock, "SELECT pg_advisory_unlock({key})"))
+	unlockB := newTestMutex(t, WithConn(db.session()), WithResourceID(174), WithSQLTemplate(OpUnlock, "SELECT pg_advisory_unlock({key})"))
+	other := newTestMutex(t, WithConn(db.session()), WithResourceID(174), WithSQLTemplate(OpUnlock, "SELECT /* other */ pg_advisory_unlock({key})"))
+
+	if unlock.so != unlockB.so {
+		t.Error("Mutexes running the same statements do not share a singleton")
+	}
+	if plain.so == unlock.so || unlock.so == other.so {
+		t.Error("Mutexes running different statements share a singleton")
+	}
+}
@@ -0,0 +1,14 @@
+package pgxmutex
+
+// PackKeys combines two int32 keys into one bigint advisory key, class in the high and
+// obj in the low 32 bits, which is how pg_locks shows the key in its classid and objid
+// columns. The packed key is a different lock than pg_advisory_lock(class, obj) with
+// two int4 arguments, which pg_locks marks with objsubid 2 instead of 1.
+func PackKeys(class, obj int32) int64 {
+	return int64(uint64(uint32(class))<<32 | uint64(uint32(obj)))
+}
+
+// UnpackKeys splits a bigint advisory key into the two int32 keys of PackKeys.
+func UnpackKeys(id int64) (class, obj int32) {
+	return int32(uint64(id) >> 32), int32(uint32(id))
+}
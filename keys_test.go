@@ -0,0 +1,65 @@
+package pgxmutex
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestPackKeysRoundTrip(t *testing.T) {
+	tests := []struct {
+		class, obj int32
+		id         int64
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{1, 0, 1 << 32},
+		{0, -1, 0xffffffff},
+		{-1, -1, -1},
+		{-1, 0, -1 << 32},
+		{math.MaxInt32, -1, math.MaxInt64},
+		{math.MinInt32, 0, math.MinInt64},
+		{math.MinInt32, math.MaxInt32, math.MinInt64 + math.MaxInt32},
+	}
+	for _, tt := range tests {
+		if id := PackKeys(tt.class, tt.obj); id != tt.id {
+			t.Errorf("PackKeys(%d, %d) = %d, want %d", tt.class, tt.obj, id, tt.id)
+		}
+		if class, obj := UnpackKeys(tt.id); class != tt.class || obj != tt.obj {
+			t.Errorf("UnpackKeys(%d) = %d, %d, want %d, %d", tt.id, class, obj, tt.class, tt.obj)
+		}
+	}
+}
+
+func TestPackKeysRoundTripAll(t *testing.T) {
+	values := []int32{math.MinInt32, math.MinInt32 + 1, -65536, -2, -1, 0, 1, 2, 65535, math.MaxInt32 - 1, math.MaxInt32}
+	seen := make(map[int64]bool)
+	for _, class := range values {
+		for _, obj := range values {
+			id := PackKeys(class, obj)
+			if seen[id] {
+				t.Fatalf("PackKeys(%d, %d) = %d collides", class, obj, id)
+			}
+			seen[id] = true
+			if c, o := UnpackKeys(id); c != class || o != obj {
+				t.Errorf("UnpackKeys(PackKeys(%d, %d)) = %d, %d", class, obj, c, o)
+			}
+		}
+	}
+}
+
+func TestPackKeysIsNotTheTwoIntLock(t *testing.T) {
+	db := newFakeDB()
+	packed := newTestMutex(t, WithConn(db.session()), WithResourceID(PackKeys(1, 2)))
+	if err := packed.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	var acquired bool
+	if err := db.session().QueryRow(context.Background(), "SELECT pg_try_advisory_lock($1, $2)", int32(1), int32(2)).Scan(&acquired); err != nil {
+		t.Fatal(err)
+	}
+	if !acquired {
+		t.Error("two-int4 lock (1, 2) blocked by the packed bigint key")
+	}
+}
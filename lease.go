@@ -0,0 +1,117 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithLeaseTable mirrors exclusive holds of the Mutex into a row of table, created with
+// LeaseTableDDL, so that lock ownership is visible to systems without access to
+// pg_locks. The row of the resource ID is written right after the lock is taken, on the
+// lock connection and thus in the transaction of transaction-level locks, within a
+// savepoint, and deleted right after the lock is released. It records the backend PID
+// of the holder, the time of acquisition and, with WithMaxHoldDuration, when the hold
+// is expected to end.
+//
+// The row is informational only; the advisory lock remains the source of truth. Failing
+// to write or delete it is logged and does not fail the lock operation, and a row may
+// outlive a session that was lost. Shared holds are not recorded.
+func WithLeaseTable(table string) Option {
+	return func(m *Mutex) error {
+		if table == "" {
+			return fmt.Errorf("lease table name must be provided")
+		}
+		m.leaseTable = quoteTable(table)
+		return nil
+	}
+}
+
+// LeaseTableDDL returns the statement creating the lease table used by WithLeaseTable.
+func LeaseTableDDL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (resource_id bigint PRIMARY KEY, holder_pid integer NOT NULL, acquired_at timestamptz NOT NULL, expires_at timestamptz)", quoteTable(table))
+}
+
+// writeLease records the exclusive hold just acquired in the lease table.
+func (m *Mutex) writeLease(ctx context.Context) {
+	if m.leaseTable == "" {
+		return
+	}
+	sql := fmt.Sprintf(
+		"INSERT INTO %s (resource_id, holder_pid, acquired_at, expires_at) VALUES ($1, pg_backend_pid(), now(), now() + $2::interval) "+
+			"ON CONFLICT (resource_id) DO UPDATE SET holder_pid = EXCLUDED.holder_pid, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at",
+		m.leaseTable,
+	)
+	var expires interface{}
+	if m.maxHold > 0 {
+		expires = fmt.Sprintf("%d microseconds", m.maxHold.Microseconds())
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if err := m.execLease(ctx, sql, m.so.id, expires); err != nil {
+		m.logger().Warn("failed to write lock lease", slog.Int64("resource_id", m.so.id), slog.Any("error", err))
+	}
+}
+
+// clearLeaseLocked deletes the lease row of this session once the exclusive lock is
+// released. A later holder has overwritten the row with its own PID, so its row is kept.
+// Must be called with m.m held.
+func (m *Mutex) clearLeaseLocked(ctx context.Context) {
+	if m.leaseTable == "" || m.conn == nil {
+		return
+	}
+	if err := m.execLease(ctx, m.deleteLeaseSQL(), m.so.id); err != nil {
+		m.logger().Warn("failed to delete lock lease", slog.Int64("resource_id", m.so.id), slog.Any("error", err))
+	}
+}
+
+// clearLeaseInTx deletes the lease row of a lock promoted into tx within tx, so that
+// the row is kept until tx commits and releases the lock. After a rollback the row stays
+// until the next holder overwrites it. Must be called with m.m held.
+func (m *Mutex) clearLeaseInTx(ctx context.Context, tx pgx.Tx) {
+	if m.leaseTable == "" {
+		return
+	}
+	exec := func(sql string, args ...interface{}) error {
+		_, err := tx.Exec(ctx, sql, args...)
+		return err
+	}
+	if err := inLeaseSavepoint(exec, m.deleteLeaseSQL(), m.so.id); err != nil {
+		m.logger().Warn("failed to delete lock lease", slog.Int64("resource_id", m.so.id), slog.Any("error", err))
+	}
+}
+
+// deleteLeaseSQL returns the statement deleting the lease row of this session.
+func (m *Mutex) deleteLeaseSQL() string {
+	return fmt.Sprintf("DELETE FROM %s WHERE resource_id = $1 AND holder_pid = pg_backend_pid()", m.leaseTable)
+}
+
+// execLease runs a lease statement. With transaction-level locks it runs in a savepoint,
+// so that a failing statement does not abort the caller's transaction. Must be called
+// with m.m held.
+func (m *Mutex) execLease(ctx context.Context, sql string, args ...interface{}) error {
+	exec := func(sql string, args ...interface{}) error {
+		_, err := m.exec(ctx, sql, args...)
+		return err
+	}
+	if m.stmts.unlock != "" {
+		return exec(sql, args...)
+	}
+	return inLeaseSavepoint(exec, sql, args...)
+}
+
+// inLeaseSavepoint runs sql with exec within a savepoint, rolling back to it on failure.
+func inLeaseSavepoint(exec func(sql string, args ...interface{}) error, sql string, args ...interface{}) error {
+	if err := exec("SAVEPOINT pgxmutex_lease"); err != nil {
+		return err
+	}
+	if err := exec(sql, args...); err != nil {
+		_ = exec("ROLLBACK TO SAVEPOINT pgxmutex_lease")
+		_ = exec("RELEASE SAVEPOINT pgxmutex_lease")
+		return err
+	}
+	return exec("RELEASE SAVEPOINT pgxmutex_lease")
+}
@@ -0,0 +1,69 @@
+package pgxmutex
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// statementIndex returns the index of the first statement containing part, or -1.
+func statementIndex(stmts []string, part string) int {
+	for i, sql := range stmts {
+		if strings.Contains(sql, part) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestLeaseDeletedAfterUnlock(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(200), WithLeaseTable("pgxmutex_leases"))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts := s.statements()
+	write, unlock, del := statementIndex(stmts, "INSERT INTO"), statementIndex(stmts, "pg_advisory_unlock("), statementIndex(stmts, "DELETE FROM")
+	if write < 0 || unlock < 0 || del < unlock {
+		t.Errorf("statements %q: want the lease written, then the lock released, then the lease deleted", stmts)
+	}
+}
+
+// recordingTx is a fakeTx recording the statements run through it.
+type recordingTx struct {
+	fakeTx
+	stmts []string
+}
+
+func (tx *recordingTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	tx.stmts = append(tx.stmts, sql)
+	return tx.fakeTx.Exec(ctx, sql, arguments...)
+}
+
+func TestPromoteToXactKeepsLeaseUntilTransactionEnds(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(200), WithLeaseTable("pgxmutex_leases"))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := &recordingTx{fakeTx: fakeTx{s: s}}
+	if err := m.PromoteToXact(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SAVEPOINT pgxmutex_lease", m.deleteLeaseSQL(), "RELEASE SAVEPOINT pgxmutex_lease"}
+	if strings.Join(tx.stmts, "; ") != strings.Join(want, "; ") {
+		t.Errorf("transaction ran %q, want the lease deleted within it: %q", tx.stmts, want)
+	}
+	// The statements of tx run on the session too
+	if n := s.count("DELETE FROM"); n != 1 {
+		t.Errorf("lease deleted %d times, want only within the transaction", n)
+	}
+}
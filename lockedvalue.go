@@ -0,0 +1,89 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// LockedValue is a value guarded by a Mutex, for the read-modify-write of a resource
+// under a distributed lock:
+//
+//	v, err := counter.Get(ctx) // locks and loads
+//	if err != nil {
+//		return err
+//	}
+//	return counter.Set(ctx, v+1) // stores and unlocks
+//
+// Every successful Get must be followed by Set, or by Release to unlock without storing.
+type LockedValue[T any] struct {
+	m     *Mutex
+	load  func(ctx context.Context) (T, error)
+	store func(ctx context.Context, v T) error
+
+	mu      sync.Mutex
+	release func() error
+}
+
+// NewLockedValue returns a LockedValue loading and storing its value with load and
+// store under m.
+func NewLockedValue[T any](m *Mutex, load func(ctx context.Context) (T, error), store func(ctx context.Context, v T) error) *LockedValue[T] {
+	return &LockedValue[T]{m: m, load: load, store: store}
+}
+
+// Get takes the lock like AcquireContext and loads the value. If load fails, the lock is
+// released again and the load error returned, joined with any release error.
+func (lv *LockedValue[T]) Get(ctx context.Context) (T, error) {
+	var zero T
+	release, err := lv.m.AcquireContext(ctx)
+	if err != nil {
+		return zero, err
+	}
+
+	v, err := lv.load(ctx)
+	if err != nil {
+		return zero, errors.Join(fmt.Errorf("failed to load locked value: %w", err), release())
+	}
+
+	lv.mu.Lock()
+	lv.release = release
+	lv.mu.Unlock()
+	return v, nil
+}
+
+// Set stores v and releases the lock taken by Get. The lock is released even if store
+// fails; the store error is returned joined with any release error. Returns
+// ErrLockNotHeld without storing if no Get is pending.
+func (lv *LockedValue[T]) Set(ctx context.Context, v T) error {
+	release, err := lv.takeRelease()
+	if err != nil {
+		return err
+	}
+	if err := lv.store(ctx, v); err != nil {
+		return errors.Join(fmt.Errorf("failed to store locked value: %w", err), release())
+	}
+	return release()
+}
+
+// Release releases the lock taken by Get without storing a value.
+func (lv *LockedValue[T]) Release() error {
+	release, err := lv.takeRelease()
+	if err != nil {
+		return err
+	}
+	return release()
+}
+
+// takeRelease returns and clears the release function of the pending Get.
+func (lv *LockedValue[T]) takeRelease() (func() error, error) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	release := lv.release
+	if release == nil {
+		return nil, ErrLockNotHeld
+	}
+	lv.release = nil
+	return release, nil
+}
@@ -0,0 +1,170 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lockPool keeps idle dedicated connections for lock sessions. A connection is
+// checked out when the Mutex becomes held and returned when it is fully released,
+// so session-level locks always stay on the session that took them.
+type lockPool struct {
+	connStr string
+	maxIdle int
+
+	mu   sync.Mutex
+	idle []*pgx.Conn
+}
+
+// lockPoolConfig is the WithLockPool configuration the lock pool is created from.
+type lockPoolConfig struct {
+	minIdle, maxIdle int
+	connStr          string
+}
+
+func newLockPool(ctx context.Context, connStr string, minIdle, maxIdle int) (*lockPool, error) {
+	p := &lockPool{connStr: connStr, maxIdle: maxIdle}
+	for i := 0; i < minIdle; i++ {
+		c, err := connect(ctx, connStr)
+		if err != nil {
+			p.close(ctx)
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		p.idle = append(p.idle, c)
+	}
+	return p, nil
+}
+
+// get returns an idle connection or dials a new one.
+func (p *lockPool) get(ctx context.Context) (*pgx.Conn, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	c, err := connect(ctx, p.connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return c, nil
+}
+
+// put returns a connection to the idle set, closing it if the set is full.
+func (p *lockPool) put(c *pgx.Conn) {
+	p.mu.Lock()
+	if len(p.idle) < p.maxIdle && !c.IsClosed() {
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	c.Close(context.Background())
+}
+
+// close closes all idle connections.
+func (p *lockPool) close(ctx context.Context) error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, c := range idle {
+		if cerr := c.Close(ctx); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close connection: %w", cerr)
+		}
+	}
+	return err
+}
+
+// pinConn starts the remote part of an acquisition, which ends with acquired,
+// acquiredShared or unpinConn. It waits for the WithRateLimit limiter, fails with
+// ErrCircuitOpen while WithCircuitBreaker is open and fails with ErrClosing once
+// DrainAndClose was called.
+func (m *Mutex) pinConn(ctx context.Context) error {
+	if m.limiter != nil {
+		if err := m.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("%w: %w", errRateLimited, err)
+		}
+	}
+	if m.breaker != nil {
+		if err := m.breaker.allow(); err != nil {
+			return err
+		}
+	}
+	if err := m.beginAcquire(); err != nil {
+		m.passBreaker()
+		return err
+	}
+	if err := m.checkoutConn(ctx); err != nil {
+		m.m.Lock()
+		m.endAcquireLocked()
+		m.m.Unlock()
+		m.passBreaker()
+		return err
+	}
+	return nil
+}
+
+// passBreaker ends an acquisition that passed the circuit breaker, if any, without
+// reaching the database.
+func (m *Mutex) passBreaker() {
+	if m.breaker != nil {
+		m.breaker.pass()
+	}
+}
+
+// checkoutConn checks a lock pool connection out for the duration of a hold and tags
+// the session for the acquisition. Without a lock pool it only tags the session.
+func (m *Mutex) checkoutConn(ctx context.Context) error {
+	if m.pool == nil {
+		return m.tagSession(ctx)
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.pins == 0 {
+		c, err := m.pool.get(ctx)
+		if err != nil {
+			return err
+		}
+		m.conn = c
+	}
+	m.pins++
+	if err := m.tagSession(ctx); err != nil {
+		m.unpinConnLocked()
+		return err
+	}
+	return nil
+}
+
+// unpinConn ends a failed acquisition, releasing its hold on the pinned connection.
+func (m *Mutex) unpinConn() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.unpinConnLocked()
+	m.endAcquireLocked()
+}
+
+// unpinConnLocked releases a hold on the pinned connection and returns it to the
+// lock pool once unused. Must be called with m.m held.
+func (m *Mutex) unpinConnLocked() {
+	if m.pool == nil {
+		return
+	}
+
+	m.pins--
+	if m.pins == 0 {
+		m.pool.put(m.conn.(*pgx.Conn))
+		m.conn = nil
+		m.labeled = false
+	}
+}
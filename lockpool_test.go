@@ -0,0 +1,45 @@
+package pgxmutex
+
+import "testing"
+
+func TestWithLockPoolValidatesSizes(t *testing.T) {
+	for _, size := range [][2]int{{-1, 1}, {0, 0}, {2, 1}} {
+		if err := WithLockPool(size[0], size[1], "postgres://localhost/none")(&Mutex{}); err == nil {
+			t.Errorf("WithLockPool(%d, %d) accepted", size[0], size[1])
+		}
+	}
+}
+
+func TestWithLockPoolReplacesConnections(t *testing.T) {
+	db := newFakeDB()
+	m := &Mutex{}
+	if err := WithConnections(db.session(), db.session())(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := WithLockPool(0, 1, "postgres://localhost/none")(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.conn != nil || m.conns != nil {
+		t.Errorf("candidate connections kept alongside the lock pool")
+	}
+}
+
+func TestLockPoolPinsConnectionPerHold(t *testing.T) {
+	m := newTestMutex(t, WithLockPool(1, 1, testConnStr(t)), WithResourceID(112))
+	if len(m.pool.idle) != 1 {
+		t.Fatalf("%d idle connections, want minIdle 1", len(m.pool.idle))
+	}
+
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.pool.idle) != 0 || m.conn == nil {
+		t.Fatalf("connection not checked out while held")
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.pool.idle) != 1 || m.conn != nil {
+		t.Errorf("connection not returned after release")
+	}
+}
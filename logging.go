@@ -0,0 +1,32 @@
+package pgxmutex
+
+import (
+	"log/slog"
+	"time"
+)
+
+// logger returns the logger of WithLogger, or the slog default logger.
+func (m *Mutex) logger() *slog.Logger {
+	if m.log != nil {
+		return m.log
+	}
+	return slog.Default()
+}
+
+// logRetry logs a failed acquisition attempt of a retry loop started at start, if
+// WithRetryLogging is set. err is nil for attempts that found the lock taken.
+func (m *Mutex) logRetry(attempt int, delay time.Duration, start time.Time, err error) {
+	if !m.retryLogging {
+		return
+	}
+	args := []any{
+		slog.Int64("resource_id", m.so.id),
+		slog.Int("attempt", attempt),
+		slog.Duration("next_delay", delay),
+		slog.Duration("elapsed", time.Since(start)),
+	}
+	if err != nil {
+		args = append(args, slog.Any("error", err))
+	}
+	m.logger().Info("lock attempt failed, retrying", args...)
+}
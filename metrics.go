@@ -0,0 +1,152 @@
+package pgxmutex
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the default upper bounds, in seconds, of the acquisition
+// latency histogram, ranging from sub-millisecond waits to long contention.
+var DefaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Histogram is a snapshot of a cumulative histogram in the Prometheus style: Counts[i]
+// is the number of observations less than or equal to Buckets[i].
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// DefaultResourceClass labels the hold time metrics of Mutexes without WithResourceClass.
+const DefaultResourceClass = "default"
+
+// Stats is a snapshot of the metrics of a Mutex.
+type Stats struct {
+	// Class is the resource class of the Mutex labeling HoldTime, as set with
+	// WithResourceClass.
+	Class string
+
+	Acquisitions uint64
+	Releases     uint64
+	Failures     uint64
+	// WaitTime is the latency of successful acquisitions, in seconds.
+	WaitTime Histogram
+	// RoundTrip is the duration of database round trips other than blocking lock
+	// statements, in seconds, recorded with WithRoundTripTiming. Unlike WaitTime, it
+	// does not include waiting for other holders.
+	RoundTrip Histogram
+	// HoldTime is how long locks were held, in seconds, from the first acquisition to
+	// the release of the last hold.
+	HoldTime Histogram
+}
+
+// metrics collects the Stats of a Mutex.
+type metrics struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func newMetrics(buckets []float64) *metrics {
+	return &metrics{stats: Stats{
+		WaitTime:  Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets))},
+		RoundTrip: Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets))},
+		HoldTime:  Histogram{Buckets: buckets, Counts: make([]uint64, len(buckets))},
+	}}
+}
+
+func (mt *metrics) observe(op Operation, d time.Duration, success bool, err error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if err != nil {
+		mt.stats.Failures++
+		return
+	}
+	switch op {
+	case OpUnlock, OpUnlockShared:
+		mt.stats.Releases++
+	default:
+		if success {
+			mt.stats.Acquisitions++
+			mt.stats.WaitTime.observe(d.Seconds())
+		}
+	}
+}
+
+func (mt *metrics) observeRoundTrip(d time.Duration) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.stats.RoundTrip.observe(d.Seconds())
+}
+
+func (mt *metrics) observeHold(d time.Duration) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.stats.HoldTime.observe(d.Seconds())
+}
+
+// observeHold records the duration of a hold that just ended.
+func (m *Mutex) observeHold(d time.Duration) {
+	if m.metrics != nil {
+		m.metrics.observeHold(d)
+	}
+	if m.otel != nil {
+		m.otel.observeHold(m.resourceClass(), d)
+	}
+}
+
+// resourceClass returns the class labeling the hold time metrics of m.
+func (m *Mutex) resourceClass() string {
+	if m.class == "" {
+		return DefaultResourceClass
+	}
+	return m.class
+}
+
+func (h *Histogram) observe(v float64) {
+	for i, b := range h.Buckets {
+		if v <= b {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += v
+}
+
+// Stats returns a snapshot of the metrics of the Mutex. It is zero unless WithMetrics
+// or WithLatencyBuckets is configured.
+func (m *Mutex) Stats() Stats {
+	if m.metrics == nil {
+		return Stats{}
+	}
+
+	m.metrics.mu.Lock()
+	defer m.metrics.mu.Unlock()
+
+	s := m.metrics.stats
+	s.Class = m.resourceClass()
+	s.WaitTime.Buckets = append([]float64(nil), s.WaitTime.Buckets...)
+	s.WaitTime.Counts = append([]uint64(nil), s.WaitTime.Counts...)
+	s.RoundTrip.Buckets = append([]float64(nil), s.RoundTrip.Buckets...)
+	s.RoundTrip.Counts = append([]uint64(nil), s.RoundTrip.Counts...)
+	s.HoldTime.Buckets = append([]float64(nil), s.HoldTime.Buckets...)
+	s.HoldTime.Counts = append([]uint64(nil), s.HoldTime.Counts...)
+	return s
+}
+
+// validateBuckets checks that buckets are non-empty and sorted strictly ascending.
+func validateBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		return fmt.Errorf("latency buckets must not be empty")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("latency buckets must be sorted in ascending order")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package pgxmutex
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestStatsHoldTime(t *testing.T) {
+	m := newTestMutex(t, WithConn(newFakeDB().session()), WithResourceID(182), WithMetrics(), WithResourceClass("jobs"))
+	for i := 0; i < 2; i++ {
+		if err := m.Lock(); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := m.Stats()
+	if s.Class != "jobs" || s.HoldTime.Count != 2 {
+		t.Errorf("Stats = class %q with %d holds, want jobs with 2", s.Class, s.HoldTime.Count)
+	}
+}
+
+// holdRecorder is a meter provider recording the attributes of the hold time histogram.
+type holdRecorder struct {
+	noop.MeterProvider
+	mu    sync.Mutex
+	attrs []attribute.Set
+}
+
+func (p *holdRecorder) Meter(string, ...metric.MeterOption) metric.Meter {
+	return holdMeter{p: p}
+}
+
+type holdMeter struct {
+	noop.Meter
+	p *holdRecorder
+}
+
+func (hm holdMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	if name != "pgxmutex.hold_time" {
+		return noop.Float64Histogram{}, nil
+	}
+	return holdHistogram{p: hm.p}, nil
+}
+
+type holdHistogram struct {
+	noop.Float64Histogram
+	p *holdRecorder
+}
+
+func (h holdHistogram) Record(_ context.Context, _ float64, options ...metric.RecordOption) {
+	h.p.mu.Lock()
+	defer h.p.mu.Unlock()
+	h.p.attrs = append(h.p.attrs, metric.NewRecordConfig(options).Attributes())
+}
+
+func TestOtelHoldTimeLabeledByClass(t *testing.T) {
+	mp := &holdRecorder{}
+	db := newFakeDB()
+	jobs := newTestMutex(t, WithConn(db.session()), WithResourceID(182), WithMeterProvider(mp), WithResourceClass("jobs"))
+	plain := newTestMutex(t, WithConn(db.session()), WithResourceID(183), WithMeterProvider(mp))
+	for _, m := range []*Mutex{jobs, plain} {
+		if err := m.Lock(); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	want := []attribute.Set{
+		attribute.NewSet(AttrResourceClass.String("jobs")),
+		attribute.NewSet(AttrResourceClass.String(DefaultResourceClass)),
+	}
+	if len(mp.attrs) != len(want) {
+		t.Fatalf("%d hold times recorded, want %d", len(mp.attrs), len(want))
+	}
+	for i := range want {
+		if !mp.attrs[i].Equals(&want[i]) {
+			t.Errorf("hold time %d labeled %v, want %v", i, mp.attrs[i].ToSlice(), want[i].ToSlice())
+		}
+	}
+}
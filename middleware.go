@@ -0,0 +1,92 @@
+package pgxmutex
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ConnHooks are called around every statement a ConnMiddleware runs. Before may return a
+// derived context, e.g. carrying a start time or a span, which is passed to the wrapped
+// connection and to After. After receives the statement error; for QueryRow it is called
+// when the row is scanned. Either hook may be nil.
+type ConnHooks struct {
+	Before func(ctx context.Context, sql string, args []interface{}) context.Context
+	After  func(ctx context.Context, sql string, args []interface{}, err error)
+}
+
+// ConnMiddleware is a Conn calling hooks around the statements of another Conn, for
+// logging, metrics or fault injection:
+//
+//	conn := pgxmutex.NewConnMiddleware(pgxConn, pgxmutex.ConnHooks{
+//		After: func(ctx context.Context, sql string, args []interface{}, err error) {
+//			log.Printf("%s %v: %v", sql, args, err)
+//		},
+//	})
+//	m, err := pgxmutex.NewMutex(pgxmutex.WithConn(conn), pgxmutex.WithResourceID(42))
+type ConnMiddleware struct {
+	conn  Conn
+	hooks ConnHooks
+}
+
+// NewConnMiddleware wraps conn with hooks.
+func NewConnMiddleware(conn Conn, hooks ConnHooks) *ConnMiddleware {
+	return &ConnMiddleware{conn: conn, hooks: hooks}
+}
+
+// Unwrap returns the wrapped connection.
+func (c *ConnMiddleware) Unwrap() Conn {
+	return c.conn
+}
+
+// unwrapConn returns the innermost connection of nested wrappers.
+func unwrapConn(c Conn) Conn {
+	for {
+		w, ok := c.(interface{ Unwrap() Conn })
+		if !ok {
+			return c
+		}
+		c = w.Unwrap()
+	}
+}
+
+func (c *ConnMiddleware) before(ctx context.Context, sql string, args []interface{}) context.Context {
+	if c.hooks.Before == nil {
+		return ctx
+	}
+	return c.hooks.Before(ctx, sql, args)
+}
+
+func (c *ConnMiddleware) after(ctx context.Context, sql string, args []interface{}, err error) {
+	if c.hooks.After != nil {
+		c.hooks.After(ctx, sql, args, err)
+	}
+}
+
+func (c *ConnMiddleware) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	ctx = c.before(ctx, sql, arguments)
+	tag, err := c.conn.Exec(ctx, sql, arguments...)
+	c.after(ctx, sql, arguments, err)
+	return tag, err
+}
+
+func (c *ConnMiddleware) QueryRow(ctx context.Context, sql string, optionsAndArgs ...interface{}) pgx.Row {
+	ctx = c.before(ctx, sql, optionsAndArgs)
+	return &middlewareRow{c: c, ctx: ctx, sql: sql, args: optionsAndArgs, row: c.conn.QueryRow(ctx, sql, optionsAndArgs...)}
+}
+
+// middlewareRow calls the After hook once the row is scanned.
+type middlewareRow struct {
+	c    *ConnMiddleware
+	ctx  context.Context
+	sql  string
+	args []interface{}
+	row  pgx.Row
+}
+
+func (r *middlewareRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	r.c.after(r.ctx, r.sql, r.args, err)
+	return err
+}
@@ -0,0 +1,21 @@
+package pgxmutex
+
+// migrationNamespace is prefixed to migration names before hashing so that
+// migration locks do not collide with other string-derived keys.
+const migrationNamespace = "pgxmutex:migration:"
+
+// MigrationLock returns a Mutex guarding the named schema migration.
+// The resource ID is derived from a stable hash of the migration name, so every
+// instance running the same migration contends for the same session-scoped
+// exclusive lock. Use TryLock to let a single instance run the migration.
+func MigrationLock(conn Conn, migrationName string) (*Mutex, error) {
+	return NewMutex(
+		WithConn(conn),
+		WithResourceID(MigrationKey(migrationName)),
+	)
+}
+
+// MigrationKey returns the advisory lock key used by MigrationLock for the named migration.
+func MigrationKey(migrationName string) int64 {
+	return hashKey(migrationNamespace + migrationName)
+}
@@ -0,0 +1,67 @@
+package pgxmutex
+
+import "fmt"
+
+// LockMode identifies the advisory lock function family used for an operation.
+type LockMode int
+
+const (
+	// ModeExclusive uses the exclusive session-level advisory lock functions.
+	ModeExclusive LockMode = iota
+	// ModeShared uses the shared session-level advisory lock functions.
+	ModeShared
+)
+
+// String returns the name of the lock mode.
+func (lm LockMode) String() string {
+	switch lm {
+	case ModeExclusive:
+		return "exclusive"
+	case ModeShared:
+		return "shared"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (lm LockMode) MarshalText() ([]byte, error) {
+	switch lm {
+	case ModeExclusive, ModeShared:
+		return []byte(lm.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown lock mode %d", lm)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (lm *LockMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "exclusive":
+		*lm = ModeExclusive
+	case "shared":
+		*lm = ModeShared
+	default:
+		return fmt.Errorf("unknown lock mode %q", text)
+	}
+	return nil
+}
+
+// AttemptResult describes a non-blocking lock attempt.
+type AttemptResult struct {
+	Acquired bool
+	Mode     LockMode
+	Key      int64
+}
+
+// TryLockResult is like TryLock but also reports the lock mode and key it attempted.
+func (m *Mutex) TryLockResult() (AttemptResult, error) {
+	acquired, err := m.TryLock()
+	return AttemptResult{Acquired: acquired, Mode: m.mode, Key: m.so.id}, err
+}
+
+// TryLockSharedResult is like TryLockShared but also reports the lock mode and key it attempted.
+func (m *Mutex) TryLockSharedResult() (AttemptResult, error) {
+	acquired, err := m.TryLockShared()
+	return AttemptResult{Acquired: acquired, Mode: ModeShared, Key: m.so.id}, err
+}
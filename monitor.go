@@ -0,0 +1,118 @@
+package pgxmutex
+
+import (
+	"fmt"
+	"time"
+)
+
+// startMonitors starts the heartbeat and loss detection routines if configured and
+// not already running. Must be called with m.m held.
+func (m *Mutex) startMonitors() {
+	if m.stopMonitors != nil || (m.heartbeat <= 0 && m.lossInterval <= 0) {
+		return
+	}
+
+	m.stopMonitors = make(chan struct{})
+	if m.heartbeat > 0 {
+		go m.monitor(m.stopMonitors, m.heartbeat, m.ping)
+	}
+	if m.lossInterval > 0 {
+		m.lost = make(chan struct{})
+		go m.monitor(m.stopMonitors, m.lossInterval, m.verifyHeld)
+	}
+}
+
+// stopMonitorsLocked stops the background routines. Must be called with m.m held.
+func (m *Mutex) stopMonitorsLocked() {
+	if m.stopMonitors != nil {
+		close(m.stopMonitors)
+		m.stopMonitors = nil
+	}
+}
+
+// monitor runs check at the given interval until stop is closed or check fails.
+// Checks are serialized with other users of the connection through m.m, and ticks
+// are skipped while an acquisition is in flight.
+func (m *Mutex) monitor(stop <-chan struct{}, interval time.Duration, check func() error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		m.m.Lock()
+		select {
+		case <-stop:
+			m.m.Unlock()
+			return
+		default:
+		}
+		if m.acquiring > 0 {
+			// A lock statement is running on the connection without m.m; the tick
+			// is skipped rather than sending a query concurrently with it.
+			m.m.Unlock()
+			continue
+		}
+		err := check()
+		m.m.Unlock()
+		if err != nil && m.reacquireLost(stop, err) {
+			err = nil
+		}
+
+		if err != nil {
+			m.lockLost(err)
+			return
+		}
+	}
+}
+
+// ping keeps the session alive so that idle timeouts and stateful proxies do not
+// drop it and release the lock. Must be called with m.m held.
+func (m *Mutex) ping() error {
+	if _, err := m.exec(m.ctx, "SELECT 1"); err != nil {
+		return fmt.Errorf("heartbeat failed, lock may be lost: %w", err)
+	}
+	return nil
+}
+
+// verifyHeld checks that the session still holds the lock. Must be called with m.m held.
+func (m *Mutex) verifyHeld() error {
+	held, err := m.isHeld(m.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify lock, lock may be lost: %w", err)
+	}
+	if !held {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// lockLost signals loss of the held lock through Done and the lock lost handler.
+func (m *Mutex) lockLost(err error) {
+	m.m.Lock()
+	if m.lost != nil {
+		select {
+		case <-m.lost:
+		default:
+			close(m.lost)
+		}
+	}
+	m.m.Unlock()
+
+	if m.onLost != nil {
+		m.onLost(err)
+	}
+}
+
+// Done returns a channel that is closed when loss detection (WithLossDetectionInterval)
+// finds that the current hold was lost. Returns nil if loss detection is not enabled
+// or the lock has never been held.
+func (m *Mutex) Done() <-chan struct{} {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.lost
+}
@@ -2,42 +2,275 @@ package pgxmutex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/time/rate"
 )
 
 // Mutex is a distributed lock based on PostgreSQL advisory locks
 type Mutex struct {
-	conn conn
-	ctx  context.Context
-	so   *singleton
+	conn      Conn
+	probe     Conn
+	conns     []Conn
+	connIdx   int
+	ownsConn  bool
+	connStr   string
+	poolStr   string
+	poolCfg   *lockPoolConfig
+	ctx       context.Context
+	closing   atomic.Bool
+	so        *singleton
+	keyArgs   []interface{}
+	stmts     *statements
+	scope     LockScope
+	fns       *LockFunctions
+	fnArgs    []interface{}
+	argsFirst bool
+	templates map[Operation]string
+	seqCtx    context.Context
+	seqName   string
+	idGen     func() int64
+
+	mode       LockMode
+	classifier func(error) LockErrorKind
+	allowPool  bool
+	noPanic    bool
+	noLocal    bool
+	actor      bool
+	pool       *lockPool
+
+	connectAttempts int
+	connectDelay    time.Duration
+
+	deadline        time.Time
+	coalesce        time.Duration
+	backoff         func(attempt int) time.Duration
+	fencingTable    string
+	leaseTable      string
+	execMode        *pgx.QueryExecMode
+	heartbeat       time.Duration
+	lossInterval    time.Duration
+	deadlockRetries int
+	reconnects      bool
+	reacquire       ReacquirePolicy
+	appName         func(context.Context) string
+	statusTTL       time.Duration
+	quietUnlock     bool
+	priority        int
+	tracking        bool
+	otel            *otelMetrics
+	log             *slog.Logger
+	retryLogging    bool
+	wakeup          *notifyWakeup
+	traceFn         func(AcquisitionEvent)
+	events          chan AcquisitionEvent
+	checkEnforce    bool
+	limiter         *rate.Limiter
+	breaker         *circuitBreaker
+	maxHold         time.Duration
+	onMaxHold       func(resourceID int64, heldFor time.Duration)
+	autoRelease     bool
+	onLost          func(error)
+	roundTrips      bool
+	onRoundTrip     func(sql string, d time.Duration)
+	interceptor     func(sql string, args []interface{}) error
+
+	metrics       *metrics
+	class         string
+	auditSink     func(AuditEvent)
+	auditMetadata map[string]string
+
+	pm sync.Mutex
+
+	// m guards the lock state below. The connection actor and lock pool serialize
+	// statements, not this state, which the heartbeat, loss monitor and hold watchdog
+	// goroutines also read and write, so m is only disabled by WithoutStateLock
+	// without them.
+	m            stateMutex
+	held         bool
+	shared       int
+	pins         int
+	heldSince    time.Time
+	holdTimer    *time.Timer
+	acquiring    int
+	drained      chan struct{}
+	heldStatus   statusEntry
+	gen          uint64
+	exclGen      uint64
+	syncErr      error
+	stopMonitors chan struct{}
+	lost         chan struct{}
+	readerSlots  map[int64]bool
+	labeled      bool
+	labelPrev    string
+	// handedOff is set once Handoff moved the connection, pool and listener to another
+	// Mutex, so that Close leaves them to the new owner.
+	handedOff bool
 }
 
 // NewMutex initializes a new Mutex with provided options.
-func NewMutex(options ...Option) (*Mutex, error) {
+func NewMutex(options ...Option) (_ *Mutex, err error) {
 	// Default configuration
-	m := &Mutex{ctx: context.Background()}
+	m := &Mutex{ctx: context.Background(), stmts: defaultStatements}
+	defer func() {
+		if err != nil {
+			m.abandonConn()
+		}
+	}()
 
 	// Apply each option
 	for _, opt := range options {
-		if err := opt(m); err != nil {
+		if err = opt(m); err != nil {
 			return nil, err
 		}
 	}
 
+	// Dial the owned connection or create the owned pool
+	switch {
+	case m.connStr != "":
+		c, err := m.dial(m.ctx)
+		if err != nil {
+			return nil, err
+		}
+		m.conn = c
+	case m.poolStr != "":
+		p, err := pgxpool.New(m.ctx, m.poolStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create connection pool: %w", err)
+		}
+		m.conn = p
+	case m.poolCfg != nil:
+		p, err := newLockPool(m.ctx, m.poolCfg.connStr, m.poolCfg.minIdle, m.poolCfg.maxIdle)
+		if err != nil {
+			return nil, err
+		}
+		m.pool = p
+	}
+
 	// Check required fields
-	if m.conn == nil {
+	if m.conn == nil && m.pool == nil {
 		return nil, fmt.Errorf("database connection must be provided")
 	}
 
+	// Session-level advisory locks are tied to a single connection, which a pool does not guarantee
+	for _, c := range append([]Conn{m.conn}, m.conns...) {
+		if _, ok := unwrapConn(c).(*pgxpool.Pool); ok && !m.allowPool {
+			return nil, fmt.Errorf("session-level advisory locks over *pgxpool.Pool are unsafe: acquire a dedicated connection or use WithAllowPoolUnsafe")
+		}
+	}
+	if len(m.conns) > 1 && (m.pool != nil || m.actor || m.reconnects) {
+		return nil, fmt.Errorf("candidate connections cannot be combined with a lock pool, connection actor or reconnects")
+	}
+
+	if m.reconnects && (m.connStr == "" || m.pool != nil || m.actor) {
+		return nil, fmt.Errorf("reacquire on reconnect requires a connection string and cannot be combined with a lock pool or connection actor")
+	}
+	if m.lossInterval > 0 && m.fns != nil {
+		return nil, fmt.Errorf("loss detection cannot be combined with custom lock functions, whose keys pg_locks cannot be queried for")
+	}
+	if m.m.off {
+		if !m.actor && m.poolCfg == nil && m.poolStr == "" {
+			return nil, fmt.Errorf("disabling the state lock requires a connection actor or a pooled connection strategy")
+		}
+		if m.heartbeat > 0 || m.lossInterval > 0 || m.maxHold > 0 || m.reconnects || m.tracking {
+			return nil, fmt.Errorf("disabling the state lock cannot be combined with heartbeats, loss detection, max hold duration, reconnects or tracking")
+		}
+	}
+	if m.wakeup != nil && m.connStr == "" {
+		return nil, fmt.Errorf("notify wakeup requires a connection string for its listen connection")
+	}
+	// Serialize all connection use through a dedicated goroutine
+	if m.actor {
+		if m.pool != nil {
+			return nil, fmt.Errorf("connection actor cannot be combined with a lock pool")
+		}
+		m.conn = newActorConn(m.conn)
+	}
+
+	if len(m.templates) > 0 {
+		m.stmts = m.stmts.withTemplates(m.templates, len(m.fnArgs)+1)
+	}
+
+	if m.seqName != "" {
+		if m.pool != nil {
+			return nil, fmt.Errorf("resource from sequence cannot be combined with a lock pool")
+		}
+		id, err := m.nextResourceID()
+		if err != nil {
+			return nil, err
+		}
+		m.so = &singleton{id: id}
+	}
+
 	// Generate a lock ID if not provided
 	if m.so == nil {
-		m.so = getSingleton(time.Now().UnixNano())
+		id := time.Now().UnixNano()
+		if m.idGen != nil {
+			id = m.idGen()
+		}
+		if id == 0 {
+			return nil, fmt.Errorf("generated resource ID must not be 0")
+		}
+		m.so = m.resolveSingleton(id)
+	} else {
+		m.so = m.resolveSingleton(m.so.id)
+	}
+	m.buildKeyArgs()
+
+	if m.checkEnforce {
+		if m.pool != nil {
+			return nil, fmt.Errorf("enforcement check cannot be combined with a lock pool")
+		}
+		if err := m.checkEnforcement(m.ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	return m, nil
 }
 
+// abandonConn stops the connection actor and closes the owned connection or lock pool
+// of a Mutex that failed to initialize. NewMutex calls it on every error return.
+func (m *Mutex) abandonConn() {
+	if m.pool != nil {
+		_ = m.pool.close(m.ctx)
+	}
+	c := m.conn
+	if a, ok := c.(*actorConn); ok {
+		a.stop()
+		c = a.conn
+	}
+	if p, ok := c.(*pgxpool.Pool); ok && m.ownsConn {
+		p.Close()
+	}
+	if c, ok := c.(interface{ Close(context.Context) error }); ok && m.ownsConn {
+		_ = c.Close(m.ctx)
+	}
+}
+
+// SetContext replaces the context used by operations without an explicit context, such
+// as Lock and Unlock, and by background routines. Operations already in flight keep the
+// context they started with. Prefer the Context variants of the operations where possible.
+func (m *Mutex) SetContext(ctx context.Context) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.ctx = ctx
+}
+
+// context returns the stored context of the Mutex.
+func (m *Mutex) context() context.Context {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.ctx
+}
+
 // SyncMutex is a wrapper around Mutex that implements sync.Locker interface.
 func (m *Mutex) SyncMutex() SyncMutex {
 	return SyncMutex{m: m}
@@ -48,41 +281,327 @@ func (m *Mutex) GetResourceID() int64 {
 	return m.so.id
 }
 
+// OwnsConn reports whether the connection was opened by the Mutex (WithConnStr)
+// and will be closed by Close. Injected connections (WithConn) are left to the caller.
+func (m *Mutex) OwnsConn() bool {
+	return m.ownsConn
+}
+
+// Close releases the locks held by the Mutex and closes the connection if it is owned by the Mutex.
+func (m *Mutex) Close() error {
+	m.m.Lock()
+	held, shared, ctx, handedOff := m.held, m.shared, m.ctx, m.handedOff
+	err := m.releaseReaderSlots(ctx)
+	m.m.Unlock()
+	if err != nil && !m.ownsConn {
+		return err
+	}
+
+	if held {
+		if _, err := m.unlockExclusive(ctx); err != nil {
+			if !m.ownsConn {
+				return err
+			}
+			// Closing the session below releases its advisory locks, so only local state is left to clean up.
+			m.m.Lock()
+			m.held = false
+			m.clearLeaseLocked(ctx)
+			m.released()
+			m.unpinConnLocked()
+			m.m.Unlock()
+			m.so.Unlock()
+		}
+	}
+	for ; shared > 0; shared-- {
+		if _, err := m.unlockShared(ctx); err != nil {
+			if !m.ownsConn {
+				return err
+			}
+			m.m.Lock()
+			m.shared--
+			m.released()
+			m.unpinConnLocked()
+			m.m.Unlock()
+			m.so.RUnlock()
+		}
+	}
+
+	if handedOff {
+		return nil
+	}
+	if m.wakeup != nil {
+		m.wakeup.mu.Lock()
+		m.closeListener()
+		m.wakeup.mu.Unlock()
+	}
+	if m.pool != nil {
+		return m.pool.close(ctx)
+	}
+	c := m.conn
+	if a, ok := c.(*actorConn); ok {
+		a.stop()
+		c = a.conn
+	}
+	if !m.ownsConn {
+		return nil
+	}
+	if p, ok := c.(*pgxpool.Pool); ok {
+		p.Close()
+		return nil
+	}
+	if c, ok := c.(interface{ Close(context.Context) error }); ok {
+		if err := c.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close connection: %w", err)
+		}
+	}
+	return nil
+}
+
 // Lock tries to acquire the advisory lock, blocking until it's available.
+// The lock is taken in the mode configured with WithLockMode, exclusive by default.
+// If a deadline is configured, the remaining time is used as lock_timeout and
+// ErrLockTimeout is returned when it expires.
 func (m *Mutex) Lock() error {
-	m.so.Lock()
-	if _, err := m.conn.Exec(m.ctx, "SELECT pg_advisory_lock($1)", m.so.id); err != nil {
+	return m.LockContext(m.context())
+}
+
+// LockContext is like Lock but uses ctx instead of the Mutex context.
+func (m *Mutex) LockContext(ctx context.Context) error {
+	if m.mode == ModeShared {
+		return m.retryDeadlocks(ctx, m.lockShared)
+	}
+	return m.retryDeadlocks(ctx, m.lockExclusive)
+}
+
+func (m *Mutex) lockExclusive(ctx context.Context) (err error) {
+	defer m.record(OpLock, m.begin(OpLock), &err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+		return ErrLockTimeout
+	}
+
+	m.so.lockWaiting(m.priority)
+	return m.lockExclusiveLocal(ctx)
+}
+
+// lockExclusiveLocal takes the exclusive advisory lock once the local singleton is
+// held, releasing the singleton on failure.
+func (m *Mutex) lockExclusiveLocal(ctx context.Context) error {
+	if m.budgetSpent(ctx) {
+		m.so.Unlock()
+		return ErrLockTimeout
+	}
+	if err := m.pinConn(ctx); err != nil {
+		m.so.Unlock()
+		return err
+	}
+	err := m.lock(ctx, m.stmts.lock)
+	m.recordBreaker(err)
+	if err != nil {
+		m.unpinConn()
 		m.so.Unlock()
+		if errors.Is(err, ErrLockTimeout) {
+			return err
+		}
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
+	m.acquired()
+	m.writeLease(ctx)
 	return nil
 }
 
 // Unlock releases the advisory lock if it's currently held.
 func (m *Mutex) Unlock() error {
-	if _, err := m.conn.Exec(m.ctx, "SELECT pg_advisory_unlock($1)", m.so.id); err != nil {
-		return fmt.Errorf("failed to release lock: %w", err)
+	return m.UnlockContext(m.context())
+}
+
+// UnlockContext is like Unlock but uses ctx instead of the Mutex context.
+// If ctx is done before the release is confirmed, ctx.Err() is returned and
+// the Mutex still considers the lock held. Unlocking a lock the Mutex does not hold
+// returns ErrLockNotHeld, and ErrLockLost if the session turned out not to hold it,
+// unless WithQuietUnlock is set.
+func (m *Mutex) UnlockContext(ctx context.Context) error {
+	res, err := m.UnlockDetailed(ctx)
+	// The session no longer held the lock, e.g. after pg_advisory_unlock_all
+	if err == nil && !res.Released && !m.quietUnlock {
+		return ErrLockLost
+	}
+	return err
+}
+
+// unlockExclusive releases the exclusive lock and reports whether the session held it.
+func (m *Mutex) unlockExclusive(ctx context.Context) (released bool, err error) {
+	return m.unlockExclusiveGen(ctx, 0)
+}
+
+// unlockExclusiveGen is unlockExclusive that, for a non-zero gen, only releases the
+// exclusive hold of that generation and returns ErrStaleToken otherwise. The check and
+// the release happen under one m.m critical section.
+func (m *Mutex) unlockExclusiveGen(ctx context.Context, gen uint64) (released bool, err error) {
+	defer m.record(OpUnlock, m.begin(OpUnlock), &err)
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if gen != 0 && (!m.held || m.exclGen != gen) {
+		return false, ErrStaleToken
+	}
+	if !m.held {
+		if m.quietUnlock {
+			return false, nil
+		}
+		return false, ErrLockNotHeld
 	}
+	// Transaction-level locks have no unlock statement, the transaction end releases them
+	released = true
+	if m.stmts.unlock != "" {
+		if err := m.queryRowKey(ctx, m.stmts.unlock).Scan(&released); err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, fmt.Errorf("failed to release lock: %w", m.classifyError(err))
+		}
+	}
+	m.held = false
+	m.clearLeaseLocked(ctx)
+	m.notifyReleased(ctx)
+	m.restoreLabelLocked(ctx)
+	m.released()
+	m.unpinConnLocked()
 	m.so.Unlock()
-	return nil
+	return released, nil
 }
 
 // TryLock attempts to acquire the advisory lock without blocking.
 // Returns an error if unable to acquire the lock.
 func (m *Mutex) TryLock() (bool, error) {
+	return m.TryLockContext(m.context())
+}
+
+// TryLockContext is like TryLock but uses ctx instead of the Mutex context.
+func (m *Mutex) TryLockContext(ctx context.Context) (bool, error) {
+	outcome, err := m.tryLockOutcome(ctx)
+	return outcome == AcquiredLocalAndRemote, err
+}
+
+// tryLockOutcome attempts the lock in the configured mode.
+func (m *Mutex) tryLockOutcome(ctx context.Context) (TryLockOutcome, error) {
+	if m.mode == ModeShared {
+		return m.tryLockShared(ctx)
+	}
+	return m.tryLockExclusive(ctx)
+}
+
+func (m *Mutex) tryLockExclusive(ctx context.Context) (outcome TryLockOutcome, err error) {
+	defer m.recordTry(OpTryLock, m.begin(OpTryLock), &outcome, &err)
+
+	if err := ctx.Err(); err != nil {
+		return BlockedRemotely, err
+	}
+
+	flight, busy, err := m.joinTry(ctx, &m.so.flight)
+	if err != nil || busy {
+		return BlockedRemotely, err
+	}
+	defer m.endTry(&m.so.flight, flight, &busy)
+
 	if !m.so.TryLock() {
-		return false, nil
+		return BlockedLocally, nil
+	}
+	if m.coalesced(&m.so.busyUntil) {
+		busy = true
+		m.so.Unlock()
+		return BlockedRemotely, nil
+	}
+	if err := m.pinConn(ctx); err != nil {
+		m.so.Unlock()
+		return BlockedRemotely, err
 	}
 
 	var acquired bool
-	if err := m.conn.QueryRow(m.ctx, "SELECT pg_try_advisory_lock($1)", m.so.id).Scan(&acquired); err != nil {
+	err = m.withFailover(func() error { return m.queryRowKey(ctx, m.stmts.tryLock).Scan(&acquired) })
+	m.recordBreaker(err)
+	if err != nil {
+		m.unpinConn()
 		m.so.Unlock()
-		return false, fmt.Errorf("failed to attempt lock acquisition: %w", err)
+		return BlockedRemotely, fmt.Errorf("failed to attempt lock acquisition: %w", m.classifyError(err))
 	}
 
 	if !acquired {
+		busy = true
+		m.markBusy(&m.so.busyUntil)
+		m.unpinConn()
 		m.so.Unlock()
+		return BlockedRemotely, nil
 	}
 
-	return acquired, nil
+	m.acquired()
+	m.writeLease(ctx)
+	return AcquiredLocalAndRemote, nil
+}
+
+// lock issues the blocking advisory lock statement. A ctx deadline or configured
+// deadline is applied as lock_timeout, so that running out of time fails the statement
+// with ErrLockTimeout instead of cancelling it and losing the session.
+func (m *Mutex) lock(ctx context.Context, sql string) error {
+	return m.withFailover(func() error { return m.lockOnce(ctx, sql) })
+}
+
+// lockOnce issues the blocking advisory lock statement on the current connection.
+func (m *Mutex) lockOnce(ctx context.Context, sql string) error {
+	budget, ok := m.lockBudget(ctx)
+	if !ok {
+		_, err := m.execKey(ctx, sql)
+		return m.deadlockError(m.classifyError(err))
+	}
+
+	if budget <= 0 {
+		return ErrLockTimeout
+	}
+	return m.deadlockError(m.classifyError(m.execWithLockTimeout(ctx, budget, sql)))
+}
+
+// acquired records that the exclusive lock is held.
+func (m *Mutex) acquired() {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if !m.held && m.shared == 0 {
+		m.heldSince = time.Now()
+		m.so.holders.Add(1)
+		m.startHoldWatchdog()
+	}
+	m.held = true
+	m.endAcquireLocked()
+	m.gen++
+	m.exclGen = m.gen
+	m.invalidateStatus()
+	m.track()
+	m.startMonitors()
+}
+
+// released stops the background routines once neither the exclusive nor any shared lock is held.
+// Must be called with m.m held.
+func (m *Mutex) released() {
+	m.invalidateStatus()
+	m.track()
+	if m.held || m.shared > 0 {
+		return
+	}
+	m.stopHoldWatchdog()
+	m.settle()
+	if !m.heldSince.IsZero() {
+		m.observeHold(time.Since(m.heldSince))
+		m.so.holders.Add(-1)
+	}
+	m.heldSince = time.Time{}
+	m.stopMonitorsLocked()
 }
@@ -0,0 +1,129 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// benchmarkResourceID is the resource ID locked by benchmarks.
+const benchmarkResourceID = 0x7067786d75746578
+
+// testConnStr returns the connection string of the test database from
+// PGXMUTEX_TEST_DATABASE_URL and skips the test if it is not set.
+func testConnStr(tb testing.TB) string {
+	tb.Helper()
+	connStr := os.Getenv("PGXMUTEX_TEST_DATABASE_URL")
+	if connStr == "" {
+		tb.Skip("PGXMUTEX_TEST_DATABASE_URL is not set")
+	}
+	return connStr
+}
+
+// newTestMutex returns a Mutex created with options and closed when the test ends.
+func newTestMutex(tb testing.TB, options ...Option) *Mutex {
+	tb.Helper()
+	m, err := NewMutex(options...)
+	if err != nil {
+		tb.Fatalf("NewMutex: %v", err)
+	}
+	tb.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// BenchmarkLockUnlock measures the client side of the Lock/Unlock hot path on a fake
+// session, so that its allocations are not hidden by database round trips.
+func BenchmarkLockUnlock(b *testing.B) {
+	m := newTestMutex(b, WithConn(newFakeDB().session()), WithResourceID(benchmarkResourceID))
+	benchmarkLockUnlock(b, m)
+}
+
+// BenchmarkLockUnlockDatabase measures the Lock/Unlock hot path on the test database.
+func BenchmarkLockUnlockDatabase(b *testing.B) {
+	m := newTestMutex(b, WithConnStr(testConnStr(b)), WithResourceID(benchmarkResourceID))
+	benchmarkLockUnlock(b, m)
+}
+
+// BenchmarkLockUnlockDatabasePrepared is BenchmarkLockUnlockDatabase with
+// WithPreparedStatements.
+func BenchmarkLockUnlockDatabasePrepared(b *testing.B) {
+	m := newTestMutex(b, WithConnStr(testConnStr(b)), WithResourceID(benchmarkResourceID), WithPreparedStatements())
+	benchmarkLockUnlock(b, m)
+}
+
+func benchmarkLockUnlock(b *testing.B, m *Mutex) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := m.Lock(); err != nil {
+			b.Fatal(err)
+		}
+		if err := m.Unlock(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestLockContextCancelledWhileWaiting(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 109)
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(109))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := m.LockContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LockContext = %v, want context.Canceled", err)
+	}
+	if err := m.Unlock(); !errors.Is(err, ErrLockNotHeld) {
+		t.Errorf("Unlock after cancelled LockContext = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestUnlockContextDone(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(109))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.UnlockContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("UnlockContext = %v, want context.Canceled", err)
+	}
+	if n := s.count("pg_advisory_unlock("); n != 0 {
+		t.Errorf("ran %d unlock statements with a done context", n)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatalf("Unlock after done UnlockContext = %v, want the lock still held", err)
+	}
+}
+
+func TestDoneContextSkipsRoundTrip(t *testing.T) {
+	ops := map[string]func(m *Mutex, ctx context.Context) error{
+		"LockContext":     func(m *Mutex, ctx context.Context) error { return m.LockContext(ctx) },
+		"LockOnce":        func(m *Mutex, ctx context.Context) error { return m.LockOnce(ctx) },
+		"TryLockContext":  func(m *Mutex, ctx context.Context) error { _, err := m.TryLockContext(ctx); return err },
+		"TryLockDetailed": func(m *Mutex, ctx context.Context) error { _, err := m.TryLockDetailed(ctx); return err },
+		"LockShared":      func(m *Mutex, ctx context.Context) error { return m.LockWith(ctx, CallMode(ModeShared)) },
+		"IsHeld":          func(m *Mutex, ctx context.Context) error { _, err := m.IsHeld(ctx); return err },
+		"Waiters":         func(m *Mutex, ctx context.Context) error { _, err := m.Waiters(ctx); return err },
+		"Available":       func(m *Mutex, ctx context.Context) error { _, err := m.Available(ctx); return err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, op := range ops {
+		t.Run(name, func(t *testing.T) {
+			s := newFakeDB().session()
+			m := newTestMutex(t, WithConn(s), WithResourceID(138))
+			if err := op(m, ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s = %v, want context.Canceled", name, err)
+			}
+			if stmts := s.statements(); len(stmts) != 0 {
+				t.Errorf("%s ran %q with a done context", name, stmts)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const sqlNotify = "SELECT pg_notify($1, '')"
+
+// notifyWakeup holds the dedicated LISTEN connection of WithNotifyWakeup.
+type notifyWakeup struct {
+	mu   sync.Mutex
+	conn *pgx.Conn
+}
+
+// notifyChannel returns the notification channel of the resource.
+func (m *Mutex) notifyChannel() string {
+	return "pgxmutex_" + strconv.FormatInt(m.so.id, 10)
+}
+
+// notifyReleased wakes waiters of other processes after a release. It is best-effort;
+// waiters fall back to polling. Must be called with m.m held.
+func (m *Mutex) notifyReleased(ctx context.Context) {
+	if m.wakeup == nil {
+		return
+	}
+	_, _ = m.exec(ctx, sqlNotify, m.notifyChannel())
+}
+
+// waitRetry waits d before the next attempt of a polling acquisition. With
+// WithNotifyWakeup it returns early when a holder using the feature releases the lock.
+func (m *Mutex) waitRetry(ctx context.Context, d time.Duration) error {
+	if m.wakeup == nil || d <= 0 || !m.wakeup.mu.TryLock() {
+		return sleepContext(ctx, d)
+	}
+	defer m.wakeup.mu.Unlock()
+
+	if err := m.listen(ctx); err != nil {
+		return sleepContext(ctx, d)
+	}
+	wctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	_, err := m.wakeup.conn.WaitForNotification(wctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		// The connection is unusable after other errors, so the next wait listens again
+		m.closeListener()
+	}
+	return nil
+}
+
+// listen opens the LISTEN connection if needed. Must be called with m.wakeup.mu held.
+func (m *Mutex) listen(ctx context.Context) error {
+	if m.wakeup.conn != nil && !m.wakeup.conn.IsClosed() {
+		return nil
+	}
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := c.Exec(ctx, "LISTEN "+pgx.Identifier{m.notifyChannel()}.Sanitize()); err != nil {
+		_ = c.Close(ctx)
+		return fmt.Errorf("failed to listen for lock releases: %w", err)
+	}
+	m.wakeup.conn = c
+	return nil
+}
+
+// closeListener closes the LISTEN connection. Must be called with m.wakeup.mu held.
+func (m *Mutex) closeListener() {
+	if m.wakeup.conn != nil {
+		_ = m.wakeup.conn.Close(context.Background())
+		m.wakeup.conn = nil
+	}
+}
@@ -0,0 +1,55 @@
+package pgxmutex
+
+import "time"
+
+// Operation names a Mutex lock operation in events.
+type Operation string
+
+const (
+	OpLock          Operation = "lock"
+	OpTryLock       Operation = "try_lock"
+	OpUnlock        Operation = "unlock"
+	OpLockShared    Operation = "lock_shared"
+	OpTryLockShared Operation = "try_lock_shared"
+	OpUnlockShared  Operation = "unlock_shared"
+)
+
+// isUnlock reports whether op releases the lock.
+func (op Operation) isUnlock() bool {
+	return op == OpUnlock || op == OpUnlockShared
+}
+
+// record reports a finished blocking lock or unlock operation. It is meant to be
+// deferred at the start of the operation with pointers to its results.
+func (m *Mutex) record(op Operation, start time.Time, errp *error) {
+	m.emit(op, start, *errp == nil, *errp)
+}
+
+// recordTry reports a finished non-blocking attempt, successful only if it acquired the lock.
+func (m *Mutex) recordTry(op Operation, start time.Time, outcome *TryLockOutcome, errp *error) {
+	m.emit(op, start, *errp == nil && *outcome == AcquiredLocalAndRemote, *errp)
+}
+
+// emit delivers an operation to the configured observers.
+func (m *Mutex) emit(op Operation, start time.Time, success bool, err error) {
+	if m.metrics != nil {
+		m.metrics.observe(op, time.Since(start), success, err)
+	}
+	if m.traceFn != nil || m.events != nil {
+		m.traceEnd(op, success, err)
+	}
+	if m.otel != nil {
+		m.otel.observe(m.so.id, op, time.Since(start), success, err)
+	}
+	if m.auditSink != nil {
+		m.audit(AuditEvent{
+			Operation:  op,
+			ResourceID: m.so.id,
+			Time:       start,
+			Duration:   time.Since(start),
+			Success:    success,
+			Err:        err,
+			Metadata:   m.auditMetadata,
+		})
+	}
+}
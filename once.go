@@ -0,0 +1,27 @@
+package pgxmutex
+
+import (
+	"context"
+	"time"
+)
+
+// LockOnce acquires the exclusive lock like LockContext, unless another Mutex or
+// goroutine of this process already holds or is acquiring the lock for the same
+// resource ID. In that case it returns ErrAlreadyHeldLocally without blocking, so the
+// caller can join the in-flight work instead of duplicating it.
+func (m *Mutex) LockOnce(ctx context.Context) (err error) {
+	defer m.record(OpLock, m.begin(OpLock), &err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+		return ErrLockTimeout
+	}
+
+	if !m.so.TryLock() {
+		return ErrAlreadyHeldLocally
+	}
+	return m.lockExclusiveLocal(ctx)
+}
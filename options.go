@@ -3,40 +3,99 @@ package pgxmutex
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
 )
 
 // Option is a functional option type for configuring Mutex.
 type Option func(*Mutex) error
 
 // WithConnStr creates new PGX connection from a connection string.
+// The connection is dialed by NewMutex once all options are applied.
 func WithConnStr(connStr string) Option {
 	return func(m *Mutex) error {
-		conn, err := pgx.Connect(context.Background(), connStr)
-		if err != nil {
-			return fmt.Errorf("failed to connect to database: %w", err)
+		m.conn = nil
+		m.conns = nil
+		m.connStr = connStr
+		m.ownsConn = true
+		m.poolStr = ""
+		return nil
+	}
+}
+
+// WithConnectRetry retries the dial of WithConnStr up to attempts times in total,
+// waiting delay before the first retry and doubling it after each one.
+func WithConnectRetry(attempts int, delay time.Duration) Option {
+	return func(m *Mutex) error {
+		if attempts < 1 || delay < 0 {
+			return fmt.Errorf("invalid connect retry: attempts must be positive and delay not negative")
 		}
-		m.conn = conn
+		m.connectAttempts = attempts
+		m.connectDelay = delay
 		return nil
 	}
 }
 
 // WithConn sets the custom DB connection.
-func WithConn(conn conn) Option {
+func WithConn(conn Conn) Option {
 	return func(m *Mutex) error {
 		m.conn = conn
+		m.conns = nil
+		m.connStr = ""
+		m.poolStr = ""
+		m.ownsConn = false
 		return nil
 	}
 }
 
-// WithResourceID sets the lock ID for advisory locking.
+// WithConnections sets candidate connections tried in order: when acquiring fails because
+// the connection is lost and no lock is held, the Mutex moves to the next one. This is
+// meant for proxies or virtual IPs in front of one logical primary. Advisory locks taken
+// on physically different databases do not coordinate with each other, so all candidates
+// must reach the same database server.
+func WithConnections(conns ...Conn) Option {
+	return func(m *Mutex) error {
+		if len(conns) == 0 {
+			return fmt.Errorf("at least one connection must be provided")
+		}
+		m.conn = conns[0]
+		m.conns = append([]Conn(nil), conns...)
+		m.connIdx = 0
+		m.connStr = ""
+		m.poolStr = ""
+		m.ownsConn = false
+		return nil
+	}
+}
+
+// WithResourceID sets the lock ID for advisory locking. Every int64 is a distinct key,
+// including negative values, as hashed IDs often are, and math.MinInt64 and
+// math.MaxInt64; only 0 is rejected, as it stands for no ID.
 func WithResourceID(id int64) Option {
 	return func(m *Mutex) error {
 		if id == 0 {
 			return fmt.Errorf("resource ID must be provided")
 		}
-		m.so = getSingleton(id)
+		// Resolved to the shared entry by NewMutex once the lock identity is complete
+		m.so = &singleton{id: id}
+		m.seqName = ""
+		return nil
+	}
+}
+
+// WithDefaultIDGenerator sets the function generating the lock ID when none is given
+// with WithResourceID or a derived variant, instead of the current time in nanoseconds,
+// which can collide when Mutexes are created at a high rate. A generated ID is unknown
+// to other processes, so it only suits locks coordinating goroutines of this process or
+// IDs handed to the other parties, e.g. from GetResourceID; shared resources need a
+// deterministic ID.
+func WithDefaultIDGenerator(fn func() int64) Option {
+	return func(m *Mutex) error {
+		m.idGen = fn
 		return nil
 	}
 }
@@ -48,3 +107,559 @@ func WithContext(ctx context.Context) Option {
 		return nil
 	}
 }
+
+// WithHeartbeat pings the connection at the given interval while the lock is held.
+// The heartbeat stops on Unlock and on the first failed ping.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(m *Mutex) error {
+		if interval <= 0 {
+			return fmt.Errorf("heartbeat interval must be positive")
+		}
+		m.heartbeat = interval
+		return nil
+	}
+}
+
+// WithLockLostHandler sets a callback invoked when the held lock is detected as lost,
+// e.g. when a heartbeat or a loss detection check fails.
+func WithLockLostHandler(fn func(error)) Option {
+	return func(m *Mutex) error {
+		m.onLost = fn
+		return nil
+	}
+}
+
+// WithDeadline sets an absolute deadline for Lock. The remaining time at call time
+// is applied as lock_timeout; a deadline in the past fails with ErrLockTimeout
+// without a round-trip.
+func WithDeadline(t time.Time) Option {
+	return func(m *Mutex) error {
+		m.deadline = t
+		return nil
+	}
+}
+
+// WithPreparedStatements makes the advisory lock queries use prepared statements
+// (pgx.QueryExecModeCacheStatement) regardless of the connection default, which
+// saves the parse step on hot lock paths. Do not use it behind proxies that do
+// not support prepared statements.
+func WithPreparedStatements() Option {
+	return func(m *Mutex) error {
+		mode := pgx.QueryExecModeCacheStatement
+		m.execMode = &mode
+		return nil
+	}
+}
+
+// WithAllowPoolUnsafe allows passing a *pgxpool.Pool to WithConn. Each statement may
+// run on a different pooled connection, so Unlock can miss the session holding the lock.
+func WithAllowPoolUnsafe() Option {
+	return func(m *Mutex) error {
+		m.allowPool = true
+		return nil
+	}
+}
+
+// WithLockMode sets the mode used by Lock, TryLock and Unlock. Defaults to ModeExclusive.
+func WithLockMode(mode LockMode) Option {
+	return func(m *Mutex) error {
+		if mode != ModeExclusive && mode != ModeShared {
+			return fmt.Errorf("unknown lock mode %d", mode)
+		}
+		m.mode = mode
+		return nil
+	}
+}
+
+// WithLockPool makes the Mutex check out a dedicated connection from a small internal
+// pool for each hold and return it on Unlock. minIdle connections are dialed upfront
+// by NewMutex and at most maxIdle idle connections are kept. Replaces WithConn and
+// WithConnStr.
+func WithLockPool(minIdle, maxIdle int, connStr string) Option {
+	return func(m *Mutex) error {
+		if minIdle < 0 || maxIdle < 1 || minIdle > maxIdle {
+			return fmt.Errorf("invalid lock pool size: need 0 <= min <= max and max >= 1")
+		}
+		m.conn = nil
+		m.conns = nil
+		m.connStr = ""
+		m.poolStr = ""
+		m.ownsConn = false
+		m.poolCfg = &lockPoolConfig{minIdle: minIdle, maxIdle: maxIdle, connStr: connStr}
+		return nil
+	}
+}
+
+// WithLossDetectionInterval verifies, at the given interval while the lock is held,
+// that the session still holds it. Each check costs one pg_locks query round-trip.
+// On loss, the channel returned by Done is closed and the lock lost handler is called.
+func WithLossDetectionInterval(d time.Duration) Option {
+	return func(m *Mutex) error {
+		if d <= 0 {
+			return fmt.Errorf("loss detection interval must be positive")
+		}
+		m.lossInterval = d
+		return nil
+	}
+}
+
+// WithErrorClassifier sets the function mapping database errors onto LockErrorKind,
+// for Postgres-compatible databases that report lock conditions with other error codes.
+// Defaults to DefaultErrorClassifier.
+func WithErrorClassifier(fn func(err error) LockErrorKind) Option {
+	return func(m *Mutex) error {
+		m.classifier = fn
+		return nil
+	}
+}
+
+// WithProbeConn sets a separate connection for read-only probing methods such as
+// IsHeld and Waiters, keeping observability queries off the lock session.
+// The locking path always uses the main connection.
+func WithProbeConn(conn Conn) Option {
+	return func(m *Mutex) error {
+		m.probe = conn
+		return nil
+	}
+}
+
+// WithNoPanic makes SyncMutex record runtime lock and unlock failures for SyncMutex.Err
+// instead of panicking.
+func WithNoPanic() Option {
+	return func(m *Mutex) error {
+		m.noPanic = true
+		return nil
+	}
+}
+
+// WithFencingTable makes Acquire issue fencing tokens from a counter table, which must
+// exist and can be created with FencingTableDDL. The name may be schema-qualified.
+func WithFencingTable(table string) Option {
+	return func(m *Mutex) error {
+		if table == "" {
+			return fmt.Errorf("fencing table name must be provided")
+		}
+		m.fencingTable = quoteTable(table)
+		return nil
+	}
+}
+
+// WithoutLocalSerialization stops the Mutex from coordinating with other Mutexes of this
+// process that use the same resource ID; every acquisition goes straight to PostgreSQL.
+// Advisory locks are reentrant per session: two such Mutexes sharing a connection both
+// acquire the lock, and it stays held until each has released it.
+func WithoutLocalSerialization() Option {
+	return func(m *Mutex) error {
+		m.noLocal = true
+		return nil
+	}
+}
+
+// WithAuditSink sets a function receiving an AuditEvent for every lock acquisition,
+// attempt and release. The sink is called synchronously after the operation; a panic
+// in the sink is recovered and does not affect the operation.
+func WithAuditSink(sink func(AuditEvent)) Option {
+	return func(m *Mutex) error {
+		m.auditSink = sink
+		return nil
+	}
+}
+
+// WithAuditMetadata attaches caller-supplied metadata, such as the acquiring identity,
+// to every AuditEvent of the Mutex.
+func WithAuditMetadata(metadata map[string]string) Option {
+	return func(m *Mutex) error {
+		m.auditMetadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			m.auditMetadata[k] = v
+		}
+		return nil
+	}
+}
+
+// WithQueryExecMode sets the pgx query exec mode used for all statements issued by the
+// Mutex, overriding the connection default. For example, QueryExecModeSimpleProtocol
+// works behind proxies that support neither prepared nor described statements.
+func WithQueryExecMode(mode pgx.QueryExecMode) Option {
+	return func(m *Mutex) error {
+		m.execMode = &mode
+		return nil
+	}
+}
+
+// WithTryLockCoalescing shares the result of a failed TryLock among attempts of this
+// process on the same resource ID: for window after an attempt found the lock taken by
+// another session, further attempts report it as taken without querying PostgreSQL.
+// Attempts made while one is in flight wait for its result and share it if it found the
+// lock taken. This trades up to window of extra acquisition latency for less load from
+// bursty probing.
+func WithTryLockCoalescing(window time.Duration) Option {
+	return func(m *Mutex) error {
+		if window <= 0 {
+			return fmt.Errorf("coalescing window must be positive")
+		}
+		m.coalesce = window
+		return nil
+	}
+}
+
+// WithResourceUUID sets the lock ID derived from a UUID, such as a uuid.UUID value.
+// The ID is the 64-bit FNV-1a hash of the 16 UUID bytes, so every service derives the
+// same ID for the same UUID. GetResourceID returns the derived ID.
+func WithResourceUUID(u [16]byte) Option {
+	return WithResourceBytes(u[:])
+}
+
+// WithResourceBytes sets the lock ID derived from an arbitrary byte identity, such as an
+// encoded composite key. The ID is the 64-bit FNV-1a hash of b, stable across processes
+// and versions, so every service encoding the identity the same way derives the same ID.
+// GetResourceID returns the derived ID.
+func WithResourceBytes(b []byte) Option {
+	return WithResourceID(hashBytes(b))
+}
+
+// WithResourceClass sets the class of the resource, e.g. "jobs" for the locks of all
+// jobs, which labels the hold time metrics so that they aggregate across the resource
+// IDs of one kind. Mutexes without a class are labeled DefaultResourceClass.
+func WithResourceClass(class string) Option {
+	return func(m *Mutex) error {
+		if class == "" {
+			return fmt.Errorf("resource class must not be empty")
+		}
+		m.class = class
+		return nil
+	}
+}
+
+// WithConnectionActor routes every statement of the Mutex through a dedicated goroutine
+// owning the connection, so the connection is never used concurrently even if the Mutex
+// is misused from several goroutines. Close stops the goroutine.
+// It cannot be combined with WithLockPool.
+func WithConnectionActor() Option {
+	return func(m *Mutex) error {
+		m.actor = true
+		return nil
+	}
+}
+
+// WithMetrics enables collection of lock metrics, reported by Stats, with
+// DefaultLatencyBuckets for the acquisition latency histogram.
+func WithMetrics() Option {
+	return WithLatencyBuckets(DefaultLatencyBuckets)
+}
+
+// WithLatencyBuckets enables collection of lock metrics with the given upper bounds,
+// in seconds, for the acquisition latency histogram. Buckets must be sorted ascending.
+func WithLatencyBuckets(buckets []float64) Option {
+	return func(m *Mutex) error {
+		if err := validateBuckets(buckets); err != nil {
+			return err
+		}
+		m.metrics = newMetrics(append([]float64(nil), buckets...))
+		return nil
+	}
+}
+
+// WithStatementInterceptor calls fn with every statement and its arguments before the
+// Mutex runs it on its lock connection, e.g. to audit them or to assert that only
+// advisory lock functions are called. An error returned by fn aborts the statement and
+// fails the operation with it. Statements on the probe and listen connections are not
+// intercepted.
+func WithStatementInterceptor(fn func(sql string, args []interface{}) error) Option {
+	return func(m *Mutex) error {
+		m.interceptor = fn
+		return nil
+	}
+}
+
+// WithRoundTripTiming measures the duration of every statement the Mutex runs on its
+// connection and passes it to fn, which may be nil. With WithMetrics, the durations are
+// also recorded in Stats.RoundTrip, except those of blocking lock statements, which
+// include waiting for other holders. This tells a slow database apart from contention.
+func WithRoundTripTiming(fn func(sql string, d time.Duration)) Option {
+	return func(m *Mutex) error {
+		m.roundTrips = true
+		m.onRoundTrip = fn
+		return nil
+	}
+}
+
+// WithBackoff sets the function returning the delay after the given failed attempt
+// (starting at 1) for LockWithRetry, e.g. for constant or jittered schedules.
+// A zero or negative delay retries immediately.
+func WithBackoff(fn func(attempt int) time.Duration) Option {
+	return func(m *Mutex) error {
+		m.backoff = fn
+		return nil
+	}
+}
+
+// WithLockFunctions replaces the advisory lock functions called by the Mutex, e.g. with
+// tenant-scoped security definer functions taking extra arguments:
+//
+//	WithLockFunctions(LockFunctions{
+//		Lock: "app.tenant_lock", TryLock: "app.tenant_try_lock", Unlock: "app.tenant_unlock",
+//		LockShared: "app.tenant_lock_shared", TryLockShared: "app.tenant_try_lock_shared",
+//		UnlockShared: "app.tenant_unlock_shared",
+//		Args: []interface{}{tenantID},
+//	})
+//
+// calls "SELECT app.tenant_lock($1, $2)" with the lock key and tenantID. As the keys the
+// functions take are unknown, pg_locks queries such as IsHeld and loss detection are not
+// supported.
+func WithLockFunctions(fns LockFunctions) Option {
+	return func(m *Mutex) error {
+		stmts, err := fns.statements()
+		if err != nil {
+			return err
+		}
+		m.stmts = stmts
+		m.scope = ScopeSession
+		fns.Args = append([]interface{}(nil), fns.Args...)
+		m.fns = &fns
+		m.fnArgs = fns.Args
+		m.argsFirst = fns.ArgsFirst
+		return nil
+	}
+}
+
+// WithSQLTemplate replaces the statement run for op, e.g. to prefix hints or wrap the
+// lock call in another function. The {key} placeholder is expanded to the bind
+// parameters of the lock key and any WithLockFunctions arguments, such as "$1" or
+// "$1, $2", so the template must be a statement taking parameters:
+//
+//	WithSQLTemplate(OpLock, "SELECT app.audited_lock({key})")
+//
+// Try and unlock templates must return a single boolean. Templates are applied after all
+// other options, on top of WithLockFunctions and WithLockScope. A Mutex with templates
+// coordinates locally only with Mutexes running the same statements for all operations,
+// so a template for any operation separates it from Mutexes without templates.
+func WithSQLTemplate(op Operation, template string) Option {
+	return func(m *Mutex) error {
+		if err := validTemplate(op, template); err != nil {
+			return err
+		}
+		if m.templates == nil {
+			m.templates = make(map[Operation]string)
+		}
+		m.templates[op] = template
+		return nil
+	}
+}
+
+// WithReacquireOnReconnect reconnects when heartbeat or loss detection finds the owned
+// connection (WithConnStr) lost, and retakes the locks the Mutex held according to policy.
+// If reconnecting or reacquiring fails, the loss is reported as without this option.
+func WithReacquireOnReconnect(policy ReacquirePolicy) Option {
+	return func(m *Mutex) error {
+		m.reconnects = true
+		m.reacquire = policy
+		return nil
+	}
+}
+
+// WithLockScope sets whether the Mutex takes session-level (the default) or
+// transaction-level locks. Transaction-level locks require the connection to be a pgx.Tx
+// or to be inside a transaction. It replaces functions set with WithLockFunctions.
+func WithLockScope(scope LockScope) Option {
+	return func(m *Mutex) error {
+		switch scope {
+		case ScopeSession:
+			m.stmts = defaultStatements
+		case ScopeXact:
+			m.stmts = xactStatements
+		default:
+			return fmt.Errorf("unknown lock scope %d", scope)
+		}
+		m.scope = scope
+		m.fns = nil
+		m.fnArgs = nil
+		return nil
+	}
+}
+
+// WithDeadlockRetry retries Lock up to attempts more times after PostgreSQL aborted it
+// with a DeadlockError, waiting a random delay before each retry.
+func WithDeadlockRetry(attempts int) Option {
+	return func(m *Mutex) error {
+		if attempts < 0 {
+			return fmt.Errorf("deadlock retry attempts must not be negative")
+		}
+		m.deadlockRetries = attempts
+		return nil
+	}
+}
+
+// WithApplicationNameFromContext sets the session application_name to fn(ctx) before
+// each acquisition, e.g. "svc/" followed by the trace ID of the request, so that
+// pg_stat_activity shows which request holds or waits for the lock. An empty name
+// leaves the setting unchanged. Only connections owned by the Mutex (WithConnStr or
+// WithLockPool) are tagged.
+func WithApplicationNameFromContext(fn func(ctx context.Context) string) Option {
+	return func(m *Mutex) error {
+		m.appName = fn
+		return nil
+	}
+}
+
+// WithStatusCache caches IsHeld and Available results for ttl, shared for Available by
+// all Mutexes of the resource in this process, to spare pg_locks from frequent polling.
+// Local locks and unlocks of the resource invalidate the cache, but changes by other
+// processes go unnoticed until it expires: callers that need an accurate answer must
+// not enable it.
+func WithStatusCache(ttl time.Duration) Option {
+	return func(m *Mutex) error {
+		if ttl < 0 {
+			return fmt.Errorf("status cache ttl must not be negative")
+		}
+		m.statusTTL = ttl
+		return nil
+	}
+}
+
+// WithQuietUnlock makes unlocking idempotent: Unlock returns nil instead of
+// ErrLockNotHeld or ErrLockLost when the lock is not held, for best-effort cleanup code.
+func WithQuietUnlock() Option {
+	return func(m *Mutex) error {
+		m.quietUnlock = true
+		return nil
+	}
+}
+
+// WithPriority sets the priority level of the Mutex among local waiters for the same
+// resource: a waiter only starts acquiring once no waiter of a higher level in this
+// process is pending. The default level is 0. This is best-effort and intra-process:
+// PostgreSQL grants advisory locks to other processes without regard to priority, and a
+// waiter that is already acquiring is not overtaken.
+func WithPriority(level int) Option {
+	return func(m *Mutex) error {
+		m.priority = level
+		return nil
+	}
+}
+
+// WithTracking registers the Mutex while it holds a lock, so that it is listed by
+// ActiveMutexes.
+func WithTracking() Option {
+	return func(m *Mutex) error {
+		m.tracking = true
+		return nil
+	}
+}
+
+// WithMeterProvider records acquisitions, releases and failures as OpenTelemetry counters
+// and the acquisition latency and hold time as histograms, with meters of mp.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(m *Mutex) error {
+		om, err := newOtelMetrics(mp)
+		if err != nil {
+			return fmt.Errorf("failed to create metric instruments: %w", err)
+		}
+		m.otel = om
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the Mutex. slog.Default() is used otherwise.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Mutex) error {
+		m.log = logger
+		return nil
+	}
+}
+
+// WithRetryLogging logs each failed attempt of the retrying acquisitions (LockWithRetry,
+// AcquireWithBackoff, AcquireWithin and WithDeadlockRetry) with the resource ID, attempt
+// number, next delay and elapsed time.
+func WithRetryLogging() Option {
+	return func(m *Mutex) error {
+		m.retryLogging = true
+		return nil
+	}
+}
+
+// WithNotifyWakeup makes the Mutex NOTIFY a channel derived from the resource ID when it
+// releases the lock, and makes its polling acquisitions (LockWithRetry and
+// AcquireWithBackoff) wait on that channel with a dedicated LISTEN connection dialed from
+// WithConnStr, so they retry as soon as the lock is released instead of after the full
+// delay. Only holders using this option notify; releases by others are noticed at the
+// next regular poll.
+func WithNotifyWakeup() Option {
+	return func(m *Mutex) error {
+		m.wakeup = &notifyWakeup{}
+		return nil
+	}
+}
+
+// WithEventChannel sends the events of WithAcquisitionTrace to the channel returned by
+// Events, buffered for size events. Sends never block lock operations: while the buffer
+// is full, new events are dropped. The channel is never closed.
+func WithEventChannel(size int) Option {
+	return func(m *Mutex) error {
+		if size < 1 {
+			return fmt.Errorf("event channel size must be positive")
+		}
+		m.events = make(chan AcquisitionEvent, size)
+		return nil
+	}
+}
+
+// WithAcquisitionTrace calls fn synchronously when a lock operation starts and when it is
+// granted, fails or releases the lock, to reconstruct the acquisition order of several
+// locks. fn runs on the calling goroutine and should be fast.
+func WithAcquisitionTrace(fn func(event AcquisitionEvent)) Option {
+	return func(m *Mutex) error {
+		m.traceFn = fn
+		return nil
+	}
+}
+
+// WithEnforcementCheck makes NewMutex verify that the database enforces advisory locks:
+// while the Mutex session holds a fresh lock, a second session must fail to take it.
+// The second session is the probe connection if set (WithProbeConn) and is dialed
+// from WithConnStr otherwise. NewMutex fails with ErrLocksNotEnforced if the check fails.
+func WithEnforcementCheck() Option {
+	return func(m *Mutex) error {
+		m.checkEnforce = true
+		return nil
+	}
+}
+
+// WithRateLimit throttles the lock operations of the Mutex to r per second with bursts
+// of burst, protecting the database from callers locking in a tight loop. Operations
+// wait for the limiter and fail if the wait would outlast the context deadline.
+func WithRateLimit(r rate.Limit, burst int) Option {
+	return func(m *Mutex) error {
+		if burst < 1 {
+			return fmt.Errorf("rate limit burst must be positive")
+		}
+		m.limiter = rate.NewLimiter(r, burst)
+		return nil
+	}
+}
+
+// WithMaxHoldDuration calls fn with the resource ID and hold time when the Mutex still
+// holds a lock d after acquiring it, to catch code that forgets to unlock. The watchdog
+// is disarmed once the Mutex holds nothing. fn runs on its own goroutine.
+func WithMaxHoldDuration(d time.Duration, fn func(resourceID int64, heldFor time.Duration)) Option {
+	return func(m *Mutex) error {
+		if d <= 0 {
+			return fmt.Errorf("max hold duration must be positive")
+		}
+		m.maxHold = d
+		m.onMaxHold = fn
+		return nil
+	}
+}
+
+// WithAutoReleaseAfterMaxHold makes the WithMaxHoldDuration watchdog also release the
+// locks of the Mutex. Another session may take the lock right away while the forgetful
+// holder still believes it is protected, so this trades a lock leak for a possible
+// violation of mutual exclusion. The release uses the Mutex connection, which must not be
+// in use by the holder at that moment.
+func WithAutoReleaseAfterMaxHold() Option {
+	return func(m *Mutex) error {
+		m.autoRelease = true
+		return nil
+	}
+}
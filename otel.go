@@ -0,0 +1,97 @@
+package pgxmutex
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Attribute keys of the OpenTelemetry metrics recorded with WithMeterProvider.
+const (
+	AttrOperation  = attribute.Key("pgxmutex.operation")
+	AttrResourceID = attribute.Key("pgxmutex.resource_id")
+	// AttrResourceClass labels the hold time with the WithResourceClass class.
+	AttrResourceClass = attribute.Key("pgxmutex.resource_class")
+)
+
+// maxOtelResources bounds the number of distinct resource ID attribute values; further
+// resources are reported as "other" to keep the metric cardinality limited.
+const maxOtelResources = 100
+
+var otelResources = struct {
+	sync.Mutex
+	seen map[int64]struct{}
+}{seen: make(map[int64]struct{})}
+
+// otelMetrics records Mutex operations with OpenTelemetry instruments.
+type otelMetrics struct {
+	acquisitions metric.Int64Counter
+	releases     metric.Int64Counter
+	failures     metric.Int64Counter
+	waitTime     metric.Float64Histogram
+	holdTime     metric.Float64Histogram
+}
+
+func newOtelMetrics(mp metric.MeterProvider) (*otelMetrics, error) {
+	meter := mp.Meter("github.com/jokruger/pgx-mutex")
+
+	var om otelMetrics
+	var err error
+	if om.acquisitions, err = meter.Int64Counter("pgxmutex.acquisitions", metric.WithDescription("Successful lock acquisitions.")); err != nil {
+		return nil, err
+	}
+	if om.releases, err = meter.Int64Counter("pgxmutex.releases", metric.WithDescription("Lock releases.")); err != nil {
+		return nil, err
+	}
+	if om.failures, err = meter.Int64Counter("pgxmutex.failures", metric.WithDescription("Failed lock operations.")); err != nil {
+		return nil, err
+	}
+	if om.waitTime, err = meter.Float64Histogram("pgxmutex.wait_time", metric.WithDescription("Latency of successful lock acquisitions."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if om.holdTime, err = meter.Float64Histogram("pgxmutex.hold_time", metric.WithDescription("Duration locks were held."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	return &om, nil
+}
+
+func (om *otelMetrics) observe(id int64, op Operation, d time.Duration, success bool, err error) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(AttrOperation.String(string(op)), AttrResourceID.String(otelResourceID(id)))
+
+	if err != nil {
+		om.failures.Add(ctx, 1, attrs)
+		return
+	}
+	switch op {
+	case OpUnlock, OpUnlockShared:
+		om.releases.Add(ctx, 1, attrs)
+	default:
+		if success {
+			om.acquisitions.Add(ctx, 1, attrs)
+			om.waitTime.Record(ctx, d.Seconds(), attrs)
+		}
+	}
+}
+
+func (om *otelMetrics) observeHold(class string, d time.Duration) {
+	om.holdTime.Record(context.Background(), d.Seconds(), metric.WithAttributes(AttrResourceClass.String(class)))
+}
+
+// otelResourceID returns the resource ID attribute value of id.
+func otelResourceID(id int64) string {
+	otelResources.Lock()
+	defer otelResources.Unlock()
+
+	if _, ok := otelResources.seen[id]; !ok {
+		if len(otelResources.seen) >= maxOtelResources {
+			return "other"
+		}
+		otelResources.seen[id] = struct{}{}
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,77 @@
+package pgxmutex
+
+import "context"
+
+// TryLockOutcome tells whether a non-blocking attempt acquired the lock and, if not,
+// who holds it.
+type TryLockOutcome int
+
+const (
+	// AcquiredLocalAndRemote means both the in-process and the advisory lock were acquired.
+	AcquiredLocalAndRemote TryLockOutcome = iota
+	// BlockedLocally means another holder in this process has the lock; retrying soon is cheap.
+	BlockedLocally
+	// BlockedRemotely means another session holds the advisory lock; backing off is advisable.
+	BlockedRemotely
+)
+
+// String returns the name of the outcome.
+func (o TryLockOutcome) String() string {
+	switch o {
+	case AcquiredLocalAndRemote:
+		return "acquired"
+	case BlockedLocally:
+		return "blocked locally"
+	case BlockedRemotely:
+		return "blocked remotely"
+	default:
+		return "unknown"
+	}
+}
+
+// TryLockDetailed is like TryLockContext but reports whether a failed attempt was
+// blocked by a holder in this process or by another session.
+func (m *Mutex) TryLockDetailed(ctx context.Context) (TryLockOutcome, error) {
+	return m.tryLockOutcome(ctx)
+}
+
+// UnlockResult describes a release.
+type UnlockResult struct {
+	// Released reports whether the database confirmed that the session held the lock.
+	// It is false when the lock was already gone, e.g. after pg_advisory_unlock_all, and
+	// for quiet unlocks of a lock the Mutex did not hold.
+	Released bool
+	Mode     LockMode
+	Key      int64
+}
+
+// UnlockDetailed is like UnlockContext but reports the result of the unlock statement
+// instead of turning a lock the session no longer held into ErrLockLost.
+// It releases the hold the Mutex has, which may be in another mode than the WithLockMode
+// one after LockWith with CallMode; the WithLockMode one goes first if both are held.
+func (m *Mutex) UnlockDetailed(ctx context.Context) (UnlockResult, error) {
+	mode := m.heldMode()
+	var released bool
+	var err error
+	if mode == ModeShared {
+		released, err = m.unlockShared(ctx)
+	} else {
+		released, err = m.unlockExclusive(ctx)
+	}
+	return UnlockResult{Released: released, Mode: mode, Key: m.so.id}, err
+}
+
+// heldMode returns the mode of the hold to release: the WithLockMode one if it is held
+// or nothing is, the other one otherwise.
+func (m *Mutex) heldMode() LockMode {
+	m.m.Lock()
+	defer m.m.Unlock()
+	switch {
+	case m.mode == ModeShared && m.shared == 0 && m.held:
+		return ModeExclusive
+	case m.mode != ModeShared && !m.held && m.shared > 0:
+		return ModeShared
+	default:
+		return m.mode
+	}
+}
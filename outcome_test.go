@@ -0,0 +1,40 @@
+package pgxmutex
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTryLockDetailed(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	a := newTestMutex(t, WithConn(db.session()), WithResourceID(121))
+	b := newTestMutex(t, WithConn(db.session()), WithResourceID(121))
+
+	if outcome, err := a.TryLockDetailed(ctx); err != nil || outcome != AcquiredLocalAndRemote {
+		t.Fatalf("first attempt = %v, %v, want acquired", outcome, err)
+	}
+	if outcome, err := b.TryLockDetailed(ctx); err != nil || outcome != BlockedLocally {
+		t.Fatalf("attempt against a holder in this process = %v, %v, want blocked locally", outcome, err)
+	}
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	holdFake(t, db, 122)
+	c := newTestMutex(t, WithConn(db.session()), WithResourceID(122))
+	if outcome, err := c.TryLockDetailed(ctx); err != nil || outcome != BlockedRemotely {
+		t.Fatalf("attempt against another session = %v, %v, want blocked remotely", outcome, err)
+	}
+}
+
+func TestTryLockDetailedShared(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	holdFake(t, db, 123)
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(123), WithLockMode(ModeShared))
+
+	if outcome, err := m.TryLockDetailed(ctx); err != nil || outcome != BlockedRemotely {
+		t.Fatalf("shared attempt against an exclusive holder = %v, %v, want blocked remotely", outcome, err)
+	}
+}
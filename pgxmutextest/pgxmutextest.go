@@ -0,0 +1,60 @@
+// Package pgxmutextest helps integration tests of pgxmutex consumers set up a real
+// database connection.
+package pgxmutextest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	pgxmutex "github.com/jokruger/pgx-mutex"
+)
+
+// ConnectForTest dials connStr and verifies that advisory locks exclude each other across
+// sessions, so that environments where they are accepted but not enforced fail before
+// the real tests run. The returned function releases all locks of the session and closes
+// the connection.
+func ConnectForTest(ctx context.Context, connStr string) (pgxmutex.Conn, func(), error) {
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	cleanup := func() {
+		_ = pgxmutex.UnlockAllUncounted(context.Background(), conn)
+		_ = conn.Close(context.Background())
+	}
+
+	if err := selfTest(ctx, conn, connStr); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return conn, cleanup, nil
+}
+
+// selfTest takes a lock on conn and checks that a second session cannot take it.
+func selfTest(ctx context.Context, conn *pgx.Conn, connStr string) error {
+	other, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		return fmt.Errorf("failed to connect second session: %w", err)
+	}
+	defer other.Close(context.Background())
+
+	key := time.Now().UnixNano()
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return fmt.Errorf("advisory locks are not supported: %w", err)
+	}
+	if !acquired {
+		return fmt.Errorf("advisory lock self-test could not take a fresh lock")
+	}
+	defer func() { _, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key) }()
+
+	if err := other.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return fmt.Errorf("advisory locks are not supported: %w", err)
+	}
+	if acquired {
+		return fmt.Errorf("advisory locks are not enforced: a second session took a held lock")
+	}
+	return nil
+}
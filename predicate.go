@@ -0,0 +1,39 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TryLockIf attempts the lock and, once acquired, evaluates predicateSQL on the lock
+// session, e.g. "SELECT status = 'pending' FROM jobs WHERE id = $1". The predicate must
+// return a single boolean; no rows counts as false. If the predicate is false or fails,
+// the lock is released again and false is returned, so the caller only proceeds when
+// the condition held while the lock was held.
+func (m *Mutex) TryLockIf(ctx context.Context, predicateSQL string, args ...interface{}) (bool, error) {
+	acquired, err := m.TryLockContext(ctx)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	var ok bool
+	m.m.Lock()
+	err = m.queryRow(ctx, predicateSQL, args...).Scan(&ok)
+	m.m.Unlock()
+	if errors.Is(err, pgx.ErrNoRows) {
+		ok, err = false, nil
+	}
+
+	if err != nil || !ok {
+		// Release even if ctx is done, commonly the reason the predicate failed
+		uerr := m.UnlockContext(context.WithoutCancel(ctx))
+		if err != nil {
+			return false, errors.Join(fmt.Errorf("failed to evaluate lock predicate: %w", err), uerr)
+		}
+		return false, uerr
+	}
+	return true, nil
+}
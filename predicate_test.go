@@ -0,0 +1,69 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestTryLockIf(t *testing.T) {
+	const pending = "SELECT status = 'pending' FROM jobs WHERE id = $1"
+	tests := []struct {
+		name    string
+		rows    map[string][]interface{}
+		fail    map[string]error
+		want    bool
+		wantErr bool
+	}{
+		{name: "true", rows: map[string][]interface{}{pending: {true}}, want: true},
+		{name: "false", rows: map[string][]interface{}{pending: {false}}},
+		{name: "no rows", fail: map[string]error{pending: pgx.ErrNoRows}},
+		{name: "error", fail: map[string]error{pending: errors.New("relation \"jobs\" does not exist")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFakeDB().session()
+			s.rows, s.fail = tt.rows, tt.fail
+			m := newTestMutex(t, WithConn(s), WithResourceID(137))
+
+			ok, err := m.TryLockIf(context.Background(), pending, 1)
+			if ok != tt.want || (err != nil) != tt.wantErr {
+				t.Fatalf("TryLockIf = %t, %v, want %t and error %t", ok, err, tt.want, tt.wantErr)
+			}
+			held, err := m.IsHeld(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if held != tt.want {
+				t.Errorf("lock held %t after TryLockIf returned %t", held, tt.want)
+			}
+		})
+	}
+}
+
+func TestTryLockIfReleasesWhenContextDone(t *testing.T) {
+	const pending = "SELECT status = 'pending' FROM jobs WHERE id = $1"
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(137))
+
+	// The ctx is cancelled while the predicate runs, failing it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.fail = map[string]error{pending: context.Canceled}
+	s.hook = func(sql string) {
+		if sql == pending {
+			cancel()
+		}
+	}
+
+	ok, err := m.TryLockIf(ctx, pending, 1)
+	if ok || !errors.Is(err, context.Canceled) {
+		t.Fatalf("TryLockIf = %t, %v, want the predicate error", ok, err)
+	}
+	s.hook = nil
+	if held, err := m.IsHeld(context.Background()); err != nil || held {
+		t.Errorf("IsHeld after TryLockIf failed = %t, %v, want released", held, err)
+	}
+}
@@ -0,0 +1,45 @@
+package pgxmutex
+
+import "sync"
+
+// priorityQueue orders the local waiters of a resource by WithPriority level: a waiter
+// only contends for the singleton once no waiter of a higher level is pending.
+type priorityQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending map[int]int
+}
+
+// wait blocks until no waiter above level is pending, then runs lock and removes the
+// caller from the queue.
+func (q *priorityQueue) wait(level int, lock func()) {
+	q.mu.Lock()
+	if q.pending == nil {
+		q.pending = make(map[int]int)
+		q.cond = sync.NewCond(&q.mu)
+	}
+	q.pending[level]++
+	for q.higherPending(level) {
+		q.cond.Wait()
+	}
+	q.mu.Unlock()
+
+	lock()
+
+	q.mu.Lock()
+	if q.pending[level]--; q.pending[level] == 0 {
+		delete(q.pending, level)
+	}
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+// higherPending reports whether a waiter above level is pending. Must be called with q.mu held.
+func (q *priorityQueue) higherPending(level int) bool {
+	for l := range q.pending {
+		if l > level {
+			return true
+		}
+	}
+	return false
+}
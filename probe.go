@@ -0,0 +1,172 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsHeld reports whether the lock session currently holds the lock in any mode, as seen by pg_locks.
+// Like Waiters, Available and AssertHeld, it fails for Mutexes with WithLockFunctions.
+func (m *Mutex) IsHeld(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.statusTTL > 0 && m.heldStatus.fresh(m.statusTTL) {
+		return m.heldStatus.value, nil
+	}
+	held, err := m.isHeld(ctx)
+	if err == nil && m.statusTTL > 0 {
+		m.heldStatus = statusEntry{valid: true, value: held, at: time.Now()}
+	}
+	return held, err
+}
+
+// AssertHeld returns ErrLockLost if the lock session no longer holds the lock the Mutex
+// believes it holds, e.g. after the session was lost and silently replaced, and
+// ErrLockNotHeld if the Mutex holds no lock. It is meant to be called right before
+// committing work the lock protects. Unlike HeldSince, which only reflects local
+// bookkeeping, the answer comes from pg_locks and bypasses WithStatusCache, at the cost
+// of one round-trip (two with WithProbeConn).
+func (m *Mutex) AssertHeld(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if !m.held && m.shared == 0 {
+		return ErrLockNotHeld
+	}
+	held, err := m.isHeld(ctx)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// isHeld queries pg_locks for the lock held by the lock session. Must be called with m.m held.
+func (m *Mutex) isHeld(ctx context.Context) (bool, error) {
+	if m.conn == nil {
+		return false, nil
+	}
+	if err := m.checkLockQuery(); err != nil {
+		return false, err
+	}
+
+	classid, objid := splitKey(m.so.id)
+	var held bool
+	if m.probe == nil {
+		if err := m.queryRow(ctx, sqlIsHeld, classid, objid).Scan(&held); err != nil {
+			return false, fmt.Errorf("failed to query lock state: %w", err)
+		}
+		return held, nil
+	}
+
+	pid, err := m.sessionPID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := m.probeScan(ctx, sqlIsHeldBy, []interface{}{&held}, classid, objid, pid); err != nil {
+		return false, fmt.Errorf("failed to query lock state: %w", err)
+	}
+	return held, nil
+}
+
+// Waiters returns the number of sessions waiting to acquire the lock.
+func (m *Mutex) Waiters(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := m.checkLockQuery(); err != nil {
+		return 0, err
+	}
+
+	classid, objid := splitKey(m.so.id)
+	var n int
+	if err := m.inspect(ctx, sqlWaiters, &n, classid, objid); err != nil {
+		return 0, fmt.Errorf("failed to query lock waiters: %w", err)
+	}
+	return n, nil
+}
+
+// Available reports whether no session currently holds the lock in any mode, without
+// acquiring it. The answer is inherently racy: the lock may be taken right after the
+// check, so use TryLock to actually claim it. Intended for monitoring code that must
+// never hold locks.
+func (m *Mutex) Available(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if err := m.checkLockQuery(); err != nil {
+		return false, err
+	}
+	if available, ok := m.cachedAvailable(); ok {
+		return available, nil
+	}
+
+	classid, objid := splitKey(m.so.id)
+	var available bool
+	if err := m.inspect(ctx, sqlAvailable, &available, classid, objid); err != nil {
+		return false, fmt.Errorf("failed to query lock availability: %w", err)
+	}
+	m.cacheAvailable(available)
+	return available, nil
+}
+
+// checkLockQuery returns an error if the lock cannot be looked up in pg_locks: the keys
+// taken by WithLockFunctions, e.g. the two int4 keys (objsubid 2) of the built-in
+// functions called with an extra argument, are unknown to the Mutex.
+func (m *Mutex) checkLockQuery() error {
+	if m.fns != nil {
+		return fmt.Errorf("lock state cannot be queried with custom lock functions")
+	}
+	return nil
+}
+
+// inspect scans the single value returned by a read-only pg_locks query into dest,
+// using the probe connection if configured and the lock session otherwise.
+func (m *Mutex) inspect(ctx context.Context, sql string, dest interface{}, args ...interface{}) error {
+	if m.probe != nil {
+		return m.probeScan(ctx, sql, []interface{}{dest}, args...)
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.conn == nil {
+		return fmt.Errorf("no connection available")
+	}
+	return m.queryRow(ctx, sql, args...).Scan(dest)
+}
+
+// sessionPID returns the backend PID of the lock session, without a round-trip when
+// the connection exposes it. Must be called with m.m held.
+func (m *Mutex) sessionPID(ctx context.Context) (uint32, error) {
+	if c, ok := m.conn.(interface{ PgConn() *pgconn.PgConn }); ok {
+		return c.PgConn().PID(), nil
+	}
+
+	var pid uint32
+	if err := m.queryRow(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		return 0, fmt.Errorf("failed to query backend pid: %w", err)
+	}
+	return pid, nil
+}
+
+// probeScan runs sql on the probe connection and scans the row into dest, serialized
+// with other probes.
+func (m *Mutex) probeScan(ctx context.Context, sql string, dest []interface{}, args ...interface{}) error {
+	m.pm.Lock()
+	defer m.pm.Unlock()
+	return m.probe.QueryRow(ctx, sql, m.queryArgs(args)...).Scan(dest...)
+}
@@ -0,0 +1,75 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// boundaryIDs are resource IDs whose pg_locks decomposition is easy to get wrong.
+var boundaryIDs = []int64{-1, -2, -1 << 32, math.MinInt64, math.MaxInt64, math.MinInt64 + 1, 1 << 31, -(1 << 31)}
+
+func TestIsHeldBoundaryIDs(t *testing.T) {
+	for _, id := range boundaryIDs {
+		db := newFakeDB()
+		m := newTestMutex(t, WithConn(db.session()), WithResourceID(id))
+		testIsHeld(t, m, id)
+	}
+}
+
+func TestIsHeldBoundaryIDsDatabase(t *testing.T) {
+	connStr := testConnStr(t)
+	for _, id := range boundaryIDs {
+		m := newTestMutex(t, WithConnStr(connStr), WithResourceID(id))
+		testIsHeld(t, m, id)
+	}
+}
+
+func testIsHeld(t *testing.T, m *Mutex, id int64) {
+	t.Helper()
+	ctx := context.Background()
+	if held, err := m.IsHeld(ctx); err != nil || held {
+		t.Fatalf("id %d: IsHeld before Lock = %t, %v", id, held, err)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatalf("id %d: Lock: %v", id, err)
+	}
+	if held, err := m.IsHeld(ctx); err != nil || !held {
+		t.Errorf("id %d: IsHeld while held = %t, %v", id, held, err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatalf("id %d: Unlock: %v", id, err)
+	}
+	if held, err := m.IsHeld(ctx); err != nil || held {
+		t.Errorf("id %d: IsHeld after Unlock = %t, %v", id, held, err)
+	}
+}
+
+func TestLockQueriesRejectedWithLockFunctions(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(143), WithLockFunctions(tenantFunctions(1)))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Unlock()
+
+	if _, err := m.IsHeld(ctx); err == nil {
+		t.Error("IsHeld succeeded with custom lock functions")
+	}
+	if _, err := m.Waiters(ctx); err == nil {
+		t.Error("Waiters succeeded with custom lock functions")
+	}
+	if _, err := m.Available(ctx); err == nil {
+		t.Error("Available succeeded with custom lock functions")
+	}
+	if err := m.AssertHeld(ctx); err == nil || errors.Is(err, ErrLockLost) {
+		t.Errorf("AssertHeld = %v, want a query error", err)
+	}
+
+	if _, err := NewMutex(WithConn(db.session()), WithLockFunctions(tenantFunctions(1)), WithLossDetectionInterval(time.Second)); err == nil {
+		t.Error("NewMutex accepted loss detection with custom lock functions")
+	}
+}
@@ -0,0 +1,59 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PromoteToXact turns the exclusive session-level lock held by the Mutex into a
+// transaction-level lock of tx, which is then held until tx commits or rolls back.
+// Afterwards the Mutex no longer holds the lock and Unlock returns ErrLockNotHeld.
+//
+// PostgreSQL cannot change the scope of a held lock, so the transaction-level lock is
+// taken first and the session-level lock released after it. Both are held by the same
+// session in between, so no other session can take the lock at any point. For this
+// tx must run on the connection of the Mutex: the transaction-level lock is only tried,
+// and a tx of another session, which would block on the session-level lock, returns an
+// error instead. If releasing the session-level lock fails, both locks stay held and
+// the Mutex still holds the session-level one.
+//
+// With WithLeaseTable the lease row is deleted within tx, so it is kept until tx ends.
+//
+// Other Mutexes of this process sharing the connection no longer wait locally for the
+// promoted lock, since the session would grant it to them reentrantly.
+func (m *Mutex) PromoteToXact(ctx context.Context, tx pgx.Tx) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.namespace() != "" || m.stmts.unlock == "" {
+		return fmt.Errorf("promotion requires the built-in session-level lock functions")
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if !m.held {
+		return ErrLockNotHeld
+	}
+
+	var acquired bool
+	if err := tx.QueryRow(ctx, sqlTryXactLock, m.so.id).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire transaction lock: %w", m.classifyError(err))
+	}
+	if !acquired {
+		return fmt.Errorf("transaction must run on the session holding the lock")
+	}
+
+	var released bool
+	if err := m.queryRowKey(ctx, m.stmts.unlock).Scan(&released); err != nil {
+		return fmt.Errorf("failed to release session lock: %w", m.classifyError(err))
+	}
+	m.held = false
+	m.clearLeaseInTx(ctx, tx)
+	m.released()
+	m.unpinConnLocked()
+	m.so.Unlock()
+	return nil
+}
@@ -0,0 +1,92 @@
+package pgxmutex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeTx is a transaction on a fakeSession. Only the methods used by the package are
+// implemented.
+type fakeTx struct {
+	pgx.Tx
+	s *fakeSession
+}
+
+func (tx fakeTx) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	return tx.s.Exec(ctx, sql, arguments...)
+}
+
+func (tx fakeTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tx.s.QueryRow(ctx, sql, args...)
+}
+
+func (tx fakeTx) Commit(ctx context.Context) error {
+	_, err := tx.s.Exec(ctx, "COMMIT")
+	return err
+}
+
+func (tx fakeTx) Rollback(ctx context.Context) error {
+	_, err := tx.s.Exec(ctx, "ROLLBACK")
+	return err
+}
+
+func TestPromoteToXactKeepsExclusion(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	s, observer := db.session(), db.session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(178))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another session tries the lock before every statement of the promotion and after it
+	excluded := func(when string) {
+		var acquired bool
+		if err := observer.QueryRow(ctx, sqlTryLock, int64(178)).Scan(&acquired); err != nil {
+			t.Fatal(err)
+		}
+		if acquired {
+			t.Errorf("another session took the lock %s", when)
+			_, _ = observer.Exec(ctx, sqlUnlock, int64(178))
+		}
+	}
+	s.hook = func(sql string) { excluded("before " + sql) }
+	if err := m.PromoteToXact(ctx, fakeTx{s: s}); err != nil {
+		t.Fatal(err)
+	}
+	s.hook = nil
+	excluded("after the promotion")
+
+	if err := m.Unlock(); err == nil {
+		t.Error("Unlock after the promotion succeeded, want ErrLockNotHeld")
+	}
+	if _, err := s.Exec(ctx, "COMMIT"); err != nil {
+		t.Fatal(err)
+	}
+	var acquired bool
+	if err := observer.QueryRow(ctx, sqlTryLock, int64(178)).Scan(&acquired); err != nil || !acquired {
+		t.Fatalf("TryLock after commit = %t, %v, want the lock released", acquired, err)
+	}
+}
+
+func TestPromoteToXactOtherSession(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(178))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PromoteToXact(ctx, fakeTx{s: db.session()}); err == nil {
+		t.Fatal("PromoteToXact with a transaction of another session succeeded")
+	}
+	if held, err := m.IsHeld(ctx); err != nil || !held {
+		t.Fatalf("session lock lost after a failed promotion: %t, %v", held, err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
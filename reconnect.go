@@ -0,0 +1,106 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReacquirePolicy selects how WithReacquireOnReconnect restores locks after a reconnect.
+type ReacquirePolicy int
+
+const (
+	// ReacquireFail tries to retake the locks without waiting and reports ErrLockLost if
+	// another session took them in the meantime.
+	ReacquireFail ReacquirePolicy = iota
+	// ReacquireBlock waits until the locks can be retaken, up to reacquireTimeout, using
+	// the Mutex context.
+	ReacquireBlock
+)
+
+// reacquireTimeout bounds reconnecting and retaking the locks after a lost connection.
+const reacquireTimeout = 30 * time.Second
+
+// reconnect replaces a lost owned connection and retakes the locks the Mutex held on it.
+// Another session may have held the lock while it was lost, so data it protects may have
+// changed. The new connection is dialed and the locks retaken without m.m held, so that
+// other operations on the Mutex are not blocked meanwhile; the connection is only swapped
+// in if the Mutex still holds the same locks by then. On failure, the new connection is
+// closed, which releases the locks already retaken on it. Must not be called with m.m held.
+func (m *Mutex) reconnect(stop <-chan struct{}) error {
+	m.m.Lock()
+	held, shared, gen, parent := m.held, m.shared, m.gen, m.ctx
+	m.m.Unlock()
+
+	ctx, cancel := context.WithTimeout(parent, reacquireTimeout)
+	defer cancel()
+
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.retake(ctx, c, held, shared); err != nil {
+		_ = c.Close(context.Background())
+		return err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	select {
+	case <-stop:
+		_ = c.Close(context.Background())
+		return ErrLockLost
+	default:
+	}
+	if m.held != held || m.shared != shared || m.gen != gen {
+		_ = c.Close(context.Background())
+		return ErrLockLost
+	}
+	if old, ok := m.conn.(interface{ Close(context.Context) error }); ok {
+		_ = old.Close(context.Background())
+	}
+	m.conn = c
+	return nil
+}
+
+// retake takes the exclusive hold, if held, and shared holds on c according to the
+// reacquire policy.
+func (m *Mutex) retake(ctx context.Context, c *pgx.Conn, held bool, shared int) error {
+	holds := shared
+	if held {
+		holds++
+	}
+	for i := 0; i < holds; i++ {
+		lock, tryLock := m.stmts.lockShared, m.stmts.tryLockShared
+		if held && i == 0 {
+			lock, tryLock = m.stmts.lock, m.stmts.tryLock
+		}
+		if m.reacquire == ReacquireBlock {
+			if _, err := c.Exec(ctx, lock, m.keyArgs...); err != nil {
+				return fmt.Errorf("failed to reacquire lock: %w", m.classifyError(err))
+			}
+			continue
+		}
+		var acquired bool
+		if err := c.QueryRow(ctx, tryLock, m.keyArgs...).Scan(&acquired); err != nil {
+			return fmt.Errorf("failed to reacquire lock: %w", m.classifyError(err))
+		}
+		if !acquired {
+			return ErrLockLost
+		}
+	}
+	return nil
+}
+
+// reacquireLost reconnects after a failed monitor check if the connection was lost and
+// WithReacquireOnReconnect is set. Reports whether the locks were restored.
+// Must not be called with m.m held.
+func (m *Mutex) reacquireLost(stop <-chan struct{}, err error) bool {
+	if !m.reconnects || !errors.Is(m.classifyError(err), ErrConnectionLost) {
+		return false
+	}
+	return m.reconnect(stop) == nil
+}
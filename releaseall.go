@@ -0,0 +1,64 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// releaseAllEnabled guards ReleaseAll.
+var releaseAllEnabled atomic.Bool
+
+// EnableReleaseAll allows or forbids ReleaseAll. It is forbidden by default.
+func EnableReleaseAll(enabled bool) {
+	releaseAllEnabled.Store(enabled)
+}
+
+// ReleaseAll is a break-glass tool releasing every lock held by the Mutexes created
+// WithTracking in this process, listed by ActiveMutexes. Each release is logged as a
+// warning with the logger of its Mutex. It fails unless enabled with EnableReleaseAll.
+//
+// ReleaseAll uses the connections of the Mutexes from the calling goroutine, so their
+// owners must not use them at the same time; an owner later unlocking a released lock
+// gets ErrLockNotHeld. Releases that fail are reported together and do not stop the others.
+func ReleaseAll(ctx context.Context) error {
+	if !releaseAllEnabled.Load() {
+		return fmt.Errorf("ReleaseAll is not enabled")
+	}
+
+	trackedMutex.Lock()
+	ms := make([]*Mutex, 0, len(tracked))
+	for m := range tracked {
+		ms = append(ms, m)
+	}
+	trackedMutex.Unlock()
+
+	var errs []error
+	for _, m := range ms {
+		errs = append(errs, m.releaseAll(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// releaseAll releases the exclusive and all shared holds of m, logging each release.
+func (m *Mutex) releaseAll(ctx context.Context) error {
+	m.m.Lock()
+	held, shared := m.held, m.shared
+	m.m.Unlock()
+
+	if held {
+		if _, err := m.unlockExclusive(ctx); err != nil {
+			return fmt.Errorf("failed to release lock %d: %w", m.so.id, err)
+		}
+		m.logger().Warn("released lock by ReleaseAll", slog.Int64("resource_id", m.so.id), slog.String("mode", "exclusive"))
+	}
+	for ; shared > 0; shared-- {
+		if _, err := m.unlockShared(ctx); err != nil {
+			return fmt.Errorf("failed to release shared lock %d: %w", m.so.id, err)
+		}
+		m.logger().Warn("released lock by ReleaseAll", slog.Int64("resource_id", m.so.id), slog.String("mode", "shared"))
+	}
+	return nil
+}
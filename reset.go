@@ -0,0 +1,49 @@
+package pgxmutex
+
+import "fmt"
+
+// Reset repoints the Mutex at a new resource ID so that it can be reused, e.g. from an
+// object pool. It fails with ErrLockHeld while the Mutex holds the lock in any mode, so
+// that a held lock is never lost track of. Reset must not run concurrently with other
+// operations on the Mutex.
+func (m *Mutex) Reset(id int64) error {
+	if id == 0 {
+		return fmt.Errorf("resource ID must be provided")
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.held || m.shared > 0 {
+		return ErrLockHeld
+	}
+	m.so = m.resolveSingleton(id)
+	m.buildKeyArgs()
+	m.syncErr = nil
+	return nil
+}
+
+// ResetSingleton replaces the in-process coordination entries of the resource ID with
+// fresh ones. It is an advanced recovery and test API for a local lock left taken, e.g.
+// by code that panicked between taking the local and the database lock, and fails with
+// ErrLockHeld if any Mutex of this process holds the lock.
+//
+// Mutexes created before keep the old entry and must be recreated or Reset to use the
+// fresh one. ResetSingleton must not run while acquisitions of the ID are in progress,
+// as they would then coordinate with different entries.
+func ResetSingleton(id int64) error {
+	singletonsMutex.Lock()
+	defer singletonsMutex.Unlock()
+
+	for key, s := range singletons {
+		if key.id == id && s.holders.Load() > 0 {
+			return ErrLockHeld
+		}
+	}
+	for key := range singletons {
+		if key.id == id {
+			singletons[key] = &singleton{id: id}
+		}
+	}
+	return nil
+}
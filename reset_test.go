@@ -0,0 +1,62 @@
+package pgxmutex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(126))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reset(127); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("Reset while held = %v, want ErrLockHeld", err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Reset(127); err != nil {
+		t.Fatal(err)
+	}
+	if id := m.GetResourceID(); id != 127 {
+		t.Fatalf("resource ID %d after Reset, want 127", id)
+	}
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	// The lock is taken on the new key, leaving the old one free
+	other := newTestMutex(t, WithConn(db.session()), WithResourceID(126))
+	if ok, err := other.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock of the old ID = %t, %v, want true", ok, err)
+	}
+	if err := m.Reset(0); err == nil {
+		t.Error("Reset to 0 accepted")
+	}
+}
+
+func TestResetSingleton(t *testing.T) {
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(1260))
+	if err := m.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResetSingleton(1260); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("ResetSingleton while held = %v, want ErrLockHeld", err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A local lock left taken, e.g. after a panic, blocks new Mutexes until reset
+	m.so.Lock()
+	if err := ResetSingleton(1260); err != nil {
+		t.Fatal(err)
+	}
+	n := newTestMutex(t, WithConn(db.session()), WithResourceID(1260))
+	if ok, err := n.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock after ResetSingleton = %t, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,74 @@
+package pgxmutex
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// connExec runs sql on the Mutex connection, timing the round trip if enabled.
+func (m *Mutex) connExec(ctx context.Context, sql string, args []interface{}) (pgconn.CommandTag, error) {
+	if err := m.intercept(sql, args); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if !m.roundTrips {
+		return m.conn.Exec(ctx, sql, args...)
+	}
+	start := time.Now()
+	tag, err := m.conn.Exec(ctx, sql, args...)
+	m.observeRoundTrip(sql, time.Since(start))
+	return tag, err
+}
+
+// connQueryRow runs sql on the Mutex connection, timing the round trip until the row
+// is scanned if enabled.
+func (m *Mutex) connQueryRow(ctx context.Context, sql string, args []interface{}) pgx.Row {
+	if err := m.intercept(sql, args); err != nil {
+		return errRow{err: err}
+	}
+	if !m.roundTrips {
+		return m.conn.QueryRow(ctx, sql, args...)
+	}
+	return &timedRow{m: m, sql: sql, start: time.Now(), row: m.conn.QueryRow(ctx, sql, args...)}
+}
+
+// intercept passes sql and its arguments, without the query exec mode, to the
+// WithStatementInterceptor hook.
+func (m *Mutex) intercept(sql string, args []interface{}) error {
+	if m.interceptor == nil {
+		return nil
+	}
+	if len(args) > 0 {
+		if _, ok := args[0].(pgx.QueryExecMode); ok {
+			args = args[1:]
+		}
+	}
+	return m.interceptor(sql, args)
+}
+
+// timedRow observes the round trip of a QueryRow once the row is scanned.
+type timedRow struct {
+	m     *Mutex
+	sql   string
+	start time.Time
+	row   pgx.Row
+}
+
+func (r *timedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	r.m.observeRoundTrip(r.sql, time.Since(r.start))
+	return err
+}
+
+// observeRoundTrip reports the round trip of sql to the callback and, unless sql is a
+// blocking lock statement whose duration includes waiting for other holders, to the metrics.
+func (m *Mutex) observeRoundTrip(sql string, d time.Duration) {
+	if m.onRoundTrip != nil {
+		m.onRoundTrip(sql, d)
+	}
+	if m.metrics != nil && sql != m.stmts.lock && sql != m.stmts.lockShared {
+		m.metrics.observeRoundTrip(d)
+	}
+}
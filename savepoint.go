@@ -0,0 +1,83 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SavepointLock is a transaction-level advisory lock taken after a savepoint by
+// LockSavepoint.
+type SavepointLock struct {
+	tx   pgx.Tx
+	name string
+}
+
+// LockSavepoint establishes the savepoint name in tx and takes the transaction-level
+// advisory lock for id after it. PostgreSQL releases locks taken after a savepoint when
+// the transaction rolls back to it, so RollbackTo undoes the work done since the savepoint
+// and releases the lock, while the rest of the transaction goes on. Otherwise the lock is
+// held until the transaction ends like any transaction-level lock; there is no way to
+// release it earlier without rolling back.
+//
+// A ctx deadline is applied as a lock_timeout for the lock statement only; the previous
+// lock_timeout of the transaction is restored afterwards.
+func LockSavepoint(ctx context.Context, tx pgx.Tx, name string, id int64) (*SavepointLock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sp := &SavepointLock{tx: tx, name: pgx.Identifier{name}.Sanitize()}
+	if _, err := tx.Exec(ctx, "SAVEPOINT "+sp.name); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	budget, timed := LockTimeoutFromContext(ctx)
+	var prev string
+	if timed {
+		if budget <= 0 {
+			sp.RollbackTo(context.Background())
+			return nil, ErrLockTimeout
+		}
+		var cur string
+		if err := tx.QueryRow(ctx, "SELECT current_setting('lock_timeout'), set_config('lock_timeout', $1, true)", lockTimeoutSetting(budget)).Scan(&prev, &cur); err != nil {
+			sp.RollbackTo(context.Background())
+			return nil, fmt.Errorf("failed to set lock timeout: %w", err)
+		}
+	}
+
+	// Rolling back to the savepoint also undoes the lock_timeout change.
+	if _, err := tx.Exec(ctx, sqlXactLock, id); err != nil {
+		sp.RollbackTo(context.Background())
+		if DefaultErrorClassifier(err) == ErrorKindTimeout {
+			return nil, fmt.Errorf("%w: %w", ErrLockTimeout, err)
+		}
+		return nil, fmt.Errorf("failed to acquire transaction lock: %w", err)
+	}
+
+	if timed {
+		if _, err := tx.Exec(ctx, "SELECT set_config('lock_timeout', $1, true)", prev); err != nil {
+			sp.RollbackTo(context.Background())
+			return nil, fmt.Errorf("failed to restore lock timeout: %w", err)
+		}
+	}
+	return sp, nil
+}
+
+// RollbackTo rolls the transaction back to the savepoint, which releases the lock.
+func (sp *SavepointLock) RollbackTo(ctx context.Context) error {
+	if _, err := sp.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT "+sp.name); err != nil {
+		return fmt.Errorf("failed to roll back to savepoint: %w", err)
+	}
+	return nil
+}
+
+// Release releases the savepoint, merging its work into the transaction. The lock
+// stays held until the transaction ends.
+func (sp *SavepointLock) Release(ctx context.Context) error {
+	if _, err := sp.tx.Exec(ctx, "RELEASE SAVEPOINT "+sp.name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
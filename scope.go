@@ -0,0 +1,68 @@
+package pgxmutex
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LockScope is the lifetime of the advisory locks taken by a Mutex.
+type LockScope int
+
+const (
+	// ScopeSession locks are held until unlocked or the session ends.
+	ScopeSession LockScope = iota
+	// ScopeXact locks are held until the transaction ends. Unlock only releases the
+	// Mutex locally, the lock itself stays held until commit or rollback.
+	ScopeXact
+)
+
+// String returns the name of the lock scope.
+func (ls LockScope) String() string {
+	switch ls {
+	case ScopeSession:
+		return "session"
+	case ScopeXact:
+		return "xact"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (ls LockScope) MarshalText() ([]byte, error) {
+	switch ls {
+	case ScopeSession, ScopeXact:
+		return []byte(ls.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown lock scope %d", ls)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (ls *LockScope) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "session":
+		*ls = ScopeSession
+	case "xact":
+		*ls = ScopeXact
+	default:
+		return fmt.Errorf("unknown lock scope %q", text)
+	}
+	return nil
+}
+
+// NewSessionMutex creates a Mutex taking exclusive session-level locks.
+func NewSessionMutex(options ...Option) (*Mutex, error) {
+	return NewMutex(append([]Option{WithLockScope(ScopeSession), WithLockMode(ModeExclusive)}, options...)...)
+}
+
+// NewXactMutex creates a Mutex taking exclusive transaction-level locks within tx.
+func NewXactMutex(tx pgx.Tx, options ...Option) (*Mutex, error) {
+	return NewMutex(append([]Option{WithConn(tx), WithLockScope(ScopeXact), WithLockMode(ModeExclusive)}, options...)...)
+}
+
+// NewSharedMutex creates a Mutex whose Lock and TryLock take shared session-level locks.
+func NewSharedMutex(options ...Option) (*Mutex, error) {
+	return NewMutex(append([]Option{WithLockScope(ScopeSession), WithLockMode(ModeShared)}, options...)...)
+}
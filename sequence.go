@@ -0,0 +1,37 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+)
+
+const sqlNextval = "SELECT nextval($1::regclass)"
+
+// WithResourceFromSequence sets the lock ID to the next value of the named sequence,
+// taken once by NewMutex, so that IDs minted across a fleet never collide. Each Mutex
+// gets a new ID, so this suits unique, ephemeral resources such as a one-shot job.
+// Other parties coordinate only if they are given the ID, e.g. passing GetResourceID to
+// WithResourceID, never by naming the same sequence. It cannot be combined with
+// WithLockPool.
+func WithResourceFromSequence(ctx context.Context, seqName string) Option {
+	return func(m *Mutex) error {
+		if seqName == "" {
+			return fmt.Errorf("sequence name must be provided")
+		}
+		m.so = nil
+		m.seqCtx, m.seqName = ctx, seqName
+		return nil
+	}
+}
+
+// nextResourceID takes the next value of the sequence of WithResourceFromSequence.
+func (m *Mutex) nextResourceID() (int64, error) {
+	var id int64
+	if err := m.queryRow(m.seqCtx, sqlNextval, m.seqName).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to take resource ID from sequence %q: %w", m.seqName, err)
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("sequence %q returned resource ID 0", m.seqName)
+	}
+	return id, nil
+}
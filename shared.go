@@ -0,0 +1,164 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LockShared acquires the advisory lock in shared mode, blocking until it's available.
+// Shared holders exclude exclusive holders but not each other.
+// The connection must not be used concurrently, so goroutines sharing one Mutex
+// should coordinate through a Gate.
+func (m *Mutex) LockShared() error {
+	return m.retryDeadlocks(m.context(), m.lockShared)
+}
+
+func (m *Mutex) lockShared(ctx context.Context) (err error) {
+	defer m.record(OpLockShared, m.begin(OpLockShared), &err)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+		return ErrLockTimeout
+	}
+
+	m.so.rlockWaiting(m.priority)
+	if m.budgetSpent(ctx) {
+		m.so.RUnlock()
+		return ErrLockTimeout
+	}
+	if err := m.pinConn(ctx); err != nil {
+		m.so.RUnlock()
+		return err
+	}
+	err = m.lock(ctx, m.stmts.lockShared)
+	m.recordBreaker(err)
+	if err != nil {
+		m.unpinConn()
+		m.so.RUnlock()
+		if errors.Is(err, ErrLockTimeout) {
+			return err
+		}
+		return fmt.Errorf("failed to acquire shared lock: %w", err)
+	}
+	m.acquiredShared()
+	return nil
+}
+
+// TryLockShared attempts to acquire the advisory lock in shared mode without blocking.
+func (m *Mutex) TryLockShared() (bool, error) {
+	outcome, err := m.tryLockShared(m.context())
+	return outcome == AcquiredLocalAndRemote, err
+}
+
+func (m *Mutex) tryLockShared(ctx context.Context) (outcome TryLockOutcome, err error) {
+	defer m.recordTry(OpTryLockShared, m.begin(OpTryLockShared), &outcome, &err)
+
+	if err := ctx.Err(); err != nil {
+		return BlockedRemotely, err
+	}
+
+	flight, busy, err := m.joinTry(ctx, &m.so.flightShared)
+	if err != nil || busy {
+		return BlockedRemotely, err
+	}
+	defer m.endTry(&m.so.flightShared, flight, &busy)
+
+	if !m.so.TryRLock() {
+		return BlockedLocally, nil
+	}
+	if m.coalesced(&m.so.busySharedUntil) {
+		busy = true
+		m.so.RUnlock()
+		return BlockedRemotely, nil
+	}
+	if err := m.pinConn(ctx); err != nil {
+		m.so.RUnlock()
+		return BlockedRemotely, err
+	}
+
+	var acquired bool
+	err = m.withFailover(func() error { return m.queryRowKey(ctx, m.stmts.tryLockShared).Scan(&acquired) })
+	m.recordBreaker(err)
+	if err != nil {
+		m.unpinConn()
+		m.so.RUnlock()
+		return BlockedRemotely, fmt.Errorf("failed to attempt shared lock acquisition: %w", m.classifyError(err))
+	}
+
+	if !acquired {
+		busy = true
+		m.markBusy(&m.so.busySharedUntil)
+		m.unpinConn()
+		m.so.RUnlock()
+		return BlockedRemotely, nil
+	}
+
+	m.acquiredShared()
+	return AcquiredLocalAndRemote, nil
+}
+
+// UnlockShared releases one shared hold of the advisory lock. A session that took the
+// shared lock several times holds it until it has been released as many times.
+// Reports whether the session actually held a shared lock; if it did not, e.g. because
+// the session was lost, the local hold is dropped anyway.
+func (m *Mutex) UnlockShared() (bool, error) {
+	return m.unlockShared(m.context())
+}
+
+func (m *Mutex) unlockShared(ctx context.Context) (released bool, err error) {
+	defer m.record(OpUnlockShared, m.begin(OpUnlockShared), &err)
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.shared == 0 {
+		if m.quietUnlock {
+			return false, nil
+		}
+		return false, ErrLockNotHeld
+	}
+	// Transaction-level locks have no unlock statement, the transaction end releases them
+	released = true
+	if m.stmts.unlockShared != "" {
+		if err := m.queryRowKey(ctx, m.stmts.unlockShared).Scan(&released); err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, fmt.Errorf("failed to release shared lock: %w", m.classifyError(err))
+		}
+	}
+	m.shared--
+	m.notifyReleased(ctx)
+	m.restoreLabelLocked(ctx)
+	m.released()
+	m.unpinConnLocked()
+	m.so.RUnlock()
+	return released, nil
+}
+
+// acquiredShared records one more shared hold of the lock.
+func (m *Mutex) acquiredShared() {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if !m.held && m.shared == 0 {
+		m.heldSince = time.Now()
+		m.so.holders.Add(1)
+		m.startHoldWatchdog()
+	}
+	m.shared++
+	m.endAcquireLocked()
+	m.gen++
+	m.invalidateStatus()
+	m.track()
+	m.startMonitors()
+}
@@ -0,0 +1,90 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnlockSharedReportsRelease(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(115))
+
+	for i := 0; i < 2; i++ {
+		if err := m.LockShared(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if released, err := m.UnlockShared(); err != nil || !released {
+			t.Fatalf("UnlockShared %d = %t, %v, want true", i, released, err)
+		}
+	}
+	if _, err := m.UnlockShared(); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("UnlockShared without a hold = %v, want ErrLockNotHeld", err)
+	}
+}
+
+func TestUnlockSharedAfterSessionLostLock(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(115))
+	if err := m.LockShared(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Exec(context.Background(), sqlUnlockAll); err != nil {
+		t.Fatal(err)
+	}
+
+	if released, err := m.UnlockShared(); err != nil || released {
+		t.Fatalf("UnlockShared = %t, %v, want false without error", released, err)
+	}
+	if _, err := m.UnlockShared(); !errors.Is(err, ErrLockNotHeld) {
+		t.Fatalf("local hold kept after UnlockShared reported false: %v", err)
+	}
+}
+
+func TestSharedLocksExcludeExclusive(t *testing.T) {
+	db := newFakeDB()
+	readers := []*Mutex{
+		newTestMutex(t, WithConn(db.session()), WithResourceID(115), WithoutLocalSerialization()),
+		newTestMutex(t, WithConn(db.session()), WithResourceID(115), WithoutLocalSerialization()),
+	}
+	writer := newTestMutex(t, WithConn(db.session()), WithResourceID(115), WithoutLocalSerialization())
+
+	for _, r := range readers {
+		if ok, err := r.TryLockShared(); err != nil || !ok {
+			t.Fatalf("TryLockShared = %t, %v", ok, err)
+		}
+	}
+	if ok, err := writer.TryLock(); err != nil || ok {
+		t.Fatalf("TryLock while shared held = %t, %v, want false", ok, err)
+	}
+	for _, r := range readers {
+		if _, err := r.UnlockShared(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := writer.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock after shared release = %t, %v, want true", ok, err)
+	}
+}
+
+func TestUnlockSharedReportsCancellation(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(115))
+	if err := m.LockShared(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.hook = func(sql string) {
+		if strings.Contains(sql, "pg_advisory_unlock_shared") {
+			cancel()
+		}
+	}
+	s.fail = map[string]error{"pg_advisory_unlock_shared": errors.New("conn closed")}
+	if _, err := m.unlockShared(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unlockShared = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,120 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// singleFlightNamespace is prefixed to single-flight keys before hashing so that they
+// do not collide with other string-derived keys.
+const singleFlightNamespace = "pgxmutex:singleflight:"
+
+// SingleFlight deduplicates work across processes: for each key, the first caller runs
+// the work and stores its result in a table, and concurrent and later callers return the
+// stored result instead of running the work again.
+//
+// Callers that find the work in progress wait for the lock of the key rather than
+// polling the table: the lock is released only after the result is stored, so the next
+// holder finds it. If the work fails, nothing is stored and the next waiter runs it.
+// Stored results are kept until deleted from the table.
+type SingleFlight struct {
+	table   string
+	options []Option
+}
+
+// NewSingleFlight returns a SingleFlight storing results in table, created with
+// SingleFlightTableDDL. Each call creates a Mutex with options, so with WithConnStr every
+// call uses a connection of its own, while with WithConn concurrent calls must not share
+// the connection. It cannot be combined with WithLockPool.
+func NewSingleFlight(table string, options ...Option) (*SingleFlight, error) {
+	if table == "" {
+		return nil, fmt.Errorf("single-flight table name must be provided")
+	}
+	m := &Mutex{ctx: context.Background(), stmts: defaultStatements}
+	for _, opt := range options {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	if m.poolCfg != nil {
+		return nil, fmt.Errorf("single flight cannot be combined with a lock pool")
+	}
+	return &SingleFlight{table: quoteTable(table), options: options}, nil
+}
+
+// Do returns the stored result of key, running fn to produce and store it if no caller
+// of any process has done so yet.
+func (s *SingleFlight) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	m, err := NewMutex(append(append([]Option(nil), s.options...), WithResourceID(SingleFlightKey(key)))...)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	if result, ok, err := s.load(ctx, m, key); err != nil || ok {
+		return result, err
+	}
+
+	if err := m.LockContext(ctx); err != nil {
+		return nil, err
+	}
+	defer m.UnlockContext(m.context())
+
+	// The previous holder may have stored the result while this caller waited
+	if result, ok, err := s.load(ctx, m, key); err != nil || ok {
+		return result, err
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store(ctx, m, key, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// load reads the stored result of key, if any.
+func (s *SingleFlight) load(ctx context.Context, m *Mutex, key string) ([]byte, bool, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	var result []byte
+	err := m.queryRow(ctx, fmt.Sprintf("SELECT result FROM %s WHERE key = $1", s.table), key).Scan(&result)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load single-flight result: %w", err)
+	}
+	return result, true, nil
+}
+
+// store saves the result of key.
+func (s *SingleFlight) store(ctx context.Context, m *Mutex, key string, result []byte) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if result == nil {
+		result = []byte{}
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (key, result) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET result = EXCLUDED.result", s.table)
+	if _, err := m.exec(ctx, sql, key, result); err != nil {
+		return fmt.Errorf("failed to store single-flight result: %w", err)
+	}
+	return nil
+}
+
+// SingleFlightKey returns the advisory lock key used by SingleFlight for key.
+func SingleFlightKey(key string) int64 {
+	return hashKey(singleFlightNamespace + key)
+}
+
+// SingleFlightTableDDL returns the statement creating the result table used by SingleFlight.
+func SingleFlightTableDDL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key text PRIMARY KEY, result bytea NOT NULL)", quoteTable(table))
+}
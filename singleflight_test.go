@@ -0,0 +1,9 @@
+package pgxmutex
+
+import "testing"
+
+func TestNewSingleFlightRejectsLockPool(t *testing.T) {
+	if _, err := NewSingleFlight("results", WithLockPool(0, 1, "postgres://localhost/none")); err == nil {
+		t.Fatal("NewSingleFlight succeeded, want lock pool rejected")
+	}
+}
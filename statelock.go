@@ -0,0 +1,36 @@
+package pgxmutex
+
+import "sync"
+
+// stateMutex guards the lock state of a Mutex. Once disabled by WithoutStateLock,
+// Lock and Unlock do nothing.
+type stateMutex struct {
+	mu  sync.Mutex
+	off bool
+}
+
+func (s *stateMutex) Lock() {
+	if !s.off {
+		s.mu.Lock()
+	}
+}
+
+func (s *stateMutex) Unlock() {
+	if !s.off {
+		s.mu.Unlock()
+	}
+}
+
+// WithoutStateLock drops the locking of the internal lock state of the Mutex, which
+// saves its cost on every operation when the connection use is already serialized by
+// WithConnectionActor, WithLockPool or a pooled WithConnectionStrategy. The Mutex must
+// then be used by one goroutine at a time: DrainAndClose and LockChan must not run
+// concurrently with its operations. It is rejected together with options
+// that start goroutines reading the state, WithHeartbeat, WithLossDetectionInterval,
+// WithMaxHoldDuration, WithReacquireOnReconnect and WithTracking.
+func WithoutStateLock() Option {
+	return func(m *Mutex) error {
+		m.m.off = true
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package pgxmutex
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithoutStateLock(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	m := newTestMutex(t, WithConn(db.session()), WithResourceID(183), WithConnectionActor(), WithoutStateLock())
+
+	for i := 0; i < 3; i++ {
+		if err := m.Lock(); err != nil {
+			t.Fatal(err)
+		}
+		if held, err := m.IsHeld(ctx); err != nil || !held {
+			t.Fatalf("IsHeld = %t, %v, want held", held, err)
+		}
+		if err := m.Unlock(); err != nil {
+			t.Fatal(err)
+		}
+		if acquired, err := m.TryLockShared(); err != nil || !acquired {
+			t.Fatalf("TryLockShared = %t, %v, want acquired", acquired, err)
+		}
+		if _, err := m.UnlockShared(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := m.Handoff()
+	if !n.m.off {
+		t.Error("Handoff enabled the state lock")
+	}
+}
+
+func TestWithoutStateLockRejected(t *testing.T) {
+	db := newFakeDB()
+	for name, options := range map[string][]Option{
+		"dedicated connection": {WithConn(db.session())},
+		"heartbeat":            {WithConn(db.session()), WithConnectionActor(), WithHeartbeat(time.Second)},
+		"loss detection":       {WithConn(db.session()), WithConnectionActor(), WithLossDetectionInterval(time.Second)},
+		"max hold":             {WithConn(db.session()), WithConnectionActor(), WithMaxHoldDuration(time.Second, nil)},
+		"tracking":             {WithConn(db.session()), WithConnectionActor(), WithTracking()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewMutex(append(options, WithoutStateLock())...); err == nil {
+				t.Fatal("NewMutex accepted WithoutStateLock")
+			}
+		})
+	}
+}
+
+// BenchmarkLockUnlockActor and BenchmarkLockUnlockActorWithoutStateLock compare the hot
+// path of a connection actor Mutex with and without the state lock.
+func BenchmarkLockUnlockActor(b *testing.B) {
+	m := newTestMutex(b, WithConn(newFakeDB().session()), WithResourceID(benchmarkResourceID), WithConnectionActor())
+	benchmarkLockUnlock(b, m)
+}
+
+func BenchmarkLockUnlockActorWithoutStateLock(b *testing.B) {
+	m := newTestMutex(b, WithConn(newFakeDB().session()), WithResourceID(benchmarkResourceID), WithConnectionActor(), WithoutStateLock())
+	benchmarkLockUnlock(b, m)
+}
@@ -0,0 +1,47 @@
+package pgxmutex
+
+import "time"
+
+// statusEntry is a cached IsHeld or Available result.
+type statusEntry struct {
+	valid bool
+	value bool
+	at    time.Time
+}
+
+// fresh reports whether the entry is set and younger than ttl.
+func (e statusEntry) fresh(ttl time.Duration) bool {
+	return e.valid && time.Since(e.at) < ttl
+}
+
+// cachedAvailable returns the cached Available result of the resource if fresh.
+func (m *Mutex) cachedAvailable() (bool, bool) {
+	if m.statusTTL <= 0 {
+		return false, false
+	}
+	m.so.statusMu.Lock()
+	defer m.so.statusMu.Unlock()
+	return m.so.available.value, m.so.available.fresh(m.statusTTL)
+}
+
+// cacheAvailable records an Available result when the status cache is enabled.
+func (m *Mutex) cacheAvailable(available bool) {
+	if m.statusTTL <= 0 {
+		return
+	}
+	m.so.statusMu.Lock()
+	defer m.so.statusMu.Unlock()
+	m.so.available = statusEntry{valid: true, value: available, at: time.Now()}
+}
+
+// invalidateStatus drops the cached results after a local lock or unlock.
+// Must be called with m.m held.
+func (m *Mutex) invalidateStatus() {
+	if m.statusTTL <= 0 {
+		return
+	}
+	m.heldStatus = statusEntry{}
+	m.so.statusMu.Lock()
+	m.so.available = statusEntry{}
+	m.so.statusMu.Unlock()
+}
@@ -0,0 +1,61 @@
+package pgxmutex
+
+import "fmt"
+
+// ConnectionStrategy selects how a Mutex obtains the connections its statements run on.
+type ConnectionStrategy int
+
+const (
+	// StrategyDedicated uses one connection owned by the Mutex for its lifetime, like
+	// WithConnStr. It has the lowest latency and costs one connection per Mutex.
+	StrategyDedicated ConnectionStrategy = iota
+	// StrategyPinnedPool checks a connection out of an internal pool for each hold and
+	// returns it on release, like WithLockPool(0, DefaultLockPoolMaxIdle, connStr). It
+	// suits many short holds with few Mutexes holding at once.
+	StrategyPinnedPool
+	// StrategyPerOpPool runs every statement on whichever connection a *pgxpool.Pool
+	// hands out. Unlock may then run on another session than the lock, so it is only
+	// accepted together with WithAllowPoolUnsafe, e.g. for try-only use.
+	StrategyPerOpPool
+)
+
+// DefaultLockPoolMaxIdle is the number of idle connections kept by StrategyPinnedPool.
+const DefaultLockPoolMaxIdle = 4
+
+// String returns the name of the connection strategy.
+func (s ConnectionStrategy) String() string {
+	switch s {
+	case StrategyDedicated:
+		return "dedicated"
+	case StrategyPinnedPool:
+		return "pinned_pool"
+	case StrategyPerOpPool:
+		return "per_op_pool"
+	default:
+		return "unknown"
+	}
+}
+
+// WithConnectionStrategy connects to connStr with strategy. It takes the place of
+// WithConn, WithConnStr or WithLockPool and must not be combined with them.
+func WithConnectionStrategy(strategy ConnectionStrategy, connStr string) Option {
+	return func(m *Mutex) error {
+		switch strategy {
+		case StrategyDedicated:
+			return WithConnStr(connStr)(m)
+		case StrategyPinnedPool:
+			return WithLockPool(0, DefaultLockPoolMaxIdle, connStr)(m)
+		case StrategyPerOpPool:
+			// The pool is created by NewMutex once all options are applied
+			m.conn = nil
+			m.conns = nil
+			m.connStr = ""
+			m.poolStr = connStr
+			m.poolCfg = nil
+			m.ownsConn = true
+			return nil
+		default:
+			return fmt.Errorf("unknown connection strategy %d", strategy)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package pgxmutex
+
+import "testing"
+
+func benchmarkStrategy(b *testing.B, strategy ConnectionStrategy, options ...Option) {
+	connStr := testConnStr(b)
+	m := newTestMutex(b, append([]Option{WithConnectionStrategy(strategy, connStr), WithResourceID(benchmarkResourceID)}, options...)...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if acquired, err := m.TryLock(); err != nil || !acquired {
+			b.Fatalf("TryLock: %v, %v", acquired, err)
+		}
+		if err := m.Unlock(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLock_DedicatedConn(b *testing.B) {
+	benchmarkStrategy(b, StrategyDedicated)
+}
+
+func BenchmarkLock_PinnedPool(b *testing.B) {
+	benchmarkStrategy(b, StrategyPinnedPool)
+}
+
+func BenchmarkLock_PerOpPool(b *testing.B) {
+	// Unlock may run on another session than the lock, which then does not release it;
+	// the benchmark measures the round trips only.
+	benchmarkStrategy(b, StrategyPerOpPool, WithAllowPoolUnsafe(), WithQuietUnlock())
+}
+
+func TestWithConnectionStrategyPerOpPoolDefersPool(t *testing.T) {
+	m := &Mutex{}
+	if err := WithConnectionStrategy(StrategyPerOpPool, "postgres://localhost/none")(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.conn != nil {
+		t.Fatal("pool created by the option, want it created by NewMutex")
+	}
+
+	// Rejected without WithAllowPoolUnsafe: the pool created by NewMutex is closed again
+	if _, err := NewMutex(WithConnectionStrategy(StrategyPerOpPool, "postgres://localhost/none")); err == nil {
+		t.Fatal("NewMutex succeeded, want pool rejected")
+	}
+}
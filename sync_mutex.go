@@ -13,21 +13,37 @@ func NewSyncMutex(opts ...Option) (*SyncMutex, error) {
 }
 
 func (sm SyncMutex) Lock() {
-	if err := sm.m.Lock(); err != nil {
-		panic(err)
-	}
+	sm.fail(sm.m.Lock())
 }
 
 func (sm SyncMutex) TryLock() bool {
 	res, err := sm.m.TryLock()
-	if err != nil {
-		panic(err)
-	}
+	sm.fail(err)
 	return res
 }
 
 func (sm SyncMutex) Unlock() {
-	if err := sm.m.Unlock(); err != nil {
-		panic(err)
+	sm.fail(sm.m.Unlock())
+}
+
+// Err returns the error of the last Lock, TryLock or Unlock call when the Mutex was
+// created with WithNoPanic, or nil if that call succeeded.
+func (sm SyncMutex) Err() error {
+	sm.m.m.Lock()
+	defer sm.m.m.Unlock()
+	return sm.m.syncErr
+}
+
+// fail panics on err, or records it for Err when panics are disabled.
+func (sm SyncMutex) fail(err error) {
+	if !sm.m.noPanic {
+		if err != nil {
+			panic(err)
+		}
+		return
 	}
+
+	sm.m.m.Lock()
+	sm.m.syncErr = err
+	sm.m.m.Unlock()
 }
@@ -0,0 +1,102 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// sqlStateLockNotAvailable is reported by PostgreSQL when lock_timeout expires.
+const sqlStateLockNotAvailable = "55P03"
+
+// lockTimeoutGrace is how long a lock statement may run past its lock_timeout before it
+// is cancelled anyway, e.g. because a proxy dropped the setting.
+const lockTimeoutGrace = time.Second
+
+// LockTimeoutFromContext converts the ctx deadline into a lock_timeout budget: the time
+// left until the deadline, rounded up to whole milliseconds, which is the granularity of
+// lock_timeout. A non-positive result means the deadline has already passed.
+// Reports false if ctx has no deadline.
+func LockTimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return roundLockTimeout(time.Until(deadline)), true
+}
+
+// roundLockTimeout rounds d up to whole milliseconds.
+func roundLockTimeout(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d.Truncate(time.Millisecond)
+	}
+	return (d + time.Millisecond - 1).Truncate(time.Millisecond)
+}
+
+// lockTimeoutSetting formats d as a lock_timeout value, rounded up to whole milliseconds.
+// The result is never lower than 1ms since 0 disables lock_timeout.
+func lockTimeoutSetting(d time.Duration) string {
+	ms := roundLockTimeout(d) / time.Millisecond
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// lockBudget returns the lock_timeout budget for a blocking acquisition as the earlier
+// of the ctx deadline and the configured deadline. Reports false if neither is set.
+func (m *Mutex) lockBudget(ctx context.Context) (time.Duration, bool) {
+	d, ok := LockTimeoutFromContext(ctx)
+	if !m.deadline.IsZero() {
+		if r := roundLockTimeout(time.Until(m.deadline)); !ok || r < d {
+			d, ok = r, true
+		}
+	}
+	return d, ok
+}
+
+// budgetSpent reports whether the lock budget ran out, e.g. while waiting for a local
+// holder, so that the lock statement would fail with ErrLockTimeout right away.
+func (m *Mutex) budgetSpent(ctx context.Context) bool {
+	budget, ok := m.lockBudget(ctx)
+	return ok && budget <= 0
+}
+
+// execWithLockTimeout runs the lock statement sql with lock_timeout set to d and
+// restores the previous setting afterwards. The statement does not run on the ctx
+// deadline, which would cancel it at the same time and close the session, but only on
+// explicit cancellation of ctx, so that lock_timeout fails it first.
+func (m *Mutex) execWithLockTimeout(ctx context.Context, d time.Duration, sql string) error {
+	var prev, cur string
+	if err := m.queryRow(ctx, "SELECT current_setting('lock_timeout'), set_config('lock_timeout', $1, false)", lockTimeoutSetting(d)).Scan(&prev, &cur); err != nil {
+		return fmt.Errorf("failed to set lock timeout: %w", err)
+	}
+
+	lctx, cancel := lockTimeoutContext(ctx, d)
+	_, err := m.execKey(lctx, sql)
+	cancel()
+
+	// Restore even if ctx is done meanwhile, so that a caller's connection keeps its setting
+	if _, rerr := m.exec(context.WithoutCancel(ctx), "SELECT set_config('lock_timeout', $1, false)", prev); rerr != nil && err == nil {
+		err = fmt.Errorf("failed to restore lock timeout: %w", rerr)
+	}
+
+	return err
+}
+
+// lockTimeoutContext returns the context of a lock statement bounded by a lock_timeout
+// of d: it drops the ctx deadline and is only cancelled when ctx is cancelled explicitly
+// or lockTimeoutGrace after d.
+func lockTimeoutContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	lctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d+lockTimeoutGrace)
+	stop := context.AfterFunc(ctx, func() {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	return lctx, func() {
+		stop()
+		cancel()
+	}
+}
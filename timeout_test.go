@@ -0,0 +1,119 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockTimeoutSetting(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Second, "1ms"},
+		{0, "1ms"},
+		{time.Nanosecond, "1ms"},
+		{time.Millisecond, "1ms"},
+		{time.Millisecond + time.Nanosecond, "2ms"},
+		{1500 * time.Microsecond, "2ms"},
+		{time.Second, "1000ms"},
+	}
+	for _, tt := range tests {
+		if got := lockTimeoutSetting(tt.d); got != tt.want {
+			t.Errorf("lockTimeoutSetting(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWithDeadlinePassed(t *testing.T) {
+	s := newFakeDB().session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(105), WithDeadline(time.Now().Add(-time.Second)))
+
+	if err := m.Lock(); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Lock = %v, want ErrLockTimeout", err)
+	}
+	if n := s.count("pg_advisory_lock("); n != 0 {
+		t.Errorf("ran %d lock statements, want none", n)
+	}
+}
+
+func TestWithDeadlineAppliesLockTimeout(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 105)
+	s := db.session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(105), WithDeadline(time.Now().Add(50*time.Millisecond)))
+
+	start := time.Now()
+	if err := m.Lock(); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Lock = %v, want ErrLockTimeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Lock returned after %v, want about the deadline", d)
+	}
+	if got := s.settings["lock_timeout"]; got != "0" {
+		t.Errorf("lock_timeout = %q after Lock, want it restored to 0", got)
+	}
+	if held, _ := m.IsHeld(context.Background()); held {
+		t.Error("lock held after ErrLockTimeout")
+	}
+}
+
+func TestContextDeadlineKeepsSession(t *testing.T) {
+	for name, lock := range map[string]func(m *Mutex) error{
+		"LockContext": func(m *Mutex) error {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			return m.LockContext(ctx)
+		},
+		"CallTimeout": func(m *Mutex) error {
+			return m.LockWith(context.Background(), CallTimeout(50*time.Millisecond))
+		},
+		"LockPreferImmediate": func(m *Mutex) error {
+			return m.LockPreferImmediate(context.Background(), 50*time.Millisecond)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := newFakeDB()
+			holdFake(t, db, 124)
+			s := db.session()
+			s.closeOnCancel = true
+			m := newTestMutex(t, WithConn(s), WithResourceID(124))
+
+			if err := lock(m); !errors.Is(err, ErrLockTimeout) {
+				t.Fatalf("lock = %v, want ErrLockTimeout", err)
+			}
+			if s.closed {
+				t.Fatal("session closed by the ctx deadline")
+			}
+			if got := s.settings["lock_timeout"]; got != "0" {
+				t.Errorf("lock_timeout = %q after the timeout, want it restored to 0", got)
+			}
+			if _, err := m.TryLock(); err != nil {
+				t.Errorf("TryLock after the timeout: %v", err)
+			}
+		})
+	}
+}
+
+func TestContextCancelInterruptsLockTimeout(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 124)
+	s := db.session()
+	m := newTestMutex(t, WithConn(s), WithResourceID(124))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := m.LockContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LockContext = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("LockContext returned after %v, want right after cancellation", d)
+	}
+	if got := s.settings["lock_timeout"]; got != "0" {
+		t.Errorf("lock_timeout = %q after cancellation, want it restored to 0", got)
+	}
+}
@@ -0,0 +1,89 @@
+package pgxmutex
+
+import "time"
+
+// AcquisitionKind is the step of a lock operation reported by WithAcquisitionTrace.
+type AcquisitionKind int
+
+const (
+	// AcquisitionRequested is emitted when a lock or lock attempt starts.
+	AcquisitionRequested AcquisitionKind = iota
+	// AcquisitionGranted is emitted when the lock was acquired.
+	AcquisitionGranted
+	// AcquisitionFailed is emitted when a lock, lock attempt or unlock did not succeed.
+	AcquisitionFailed
+	// AcquisitionReleased is emitted when the lock was released.
+	AcquisitionReleased
+)
+
+// String returns the name of the kind.
+func (k AcquisitionKind) String() string {
+	switch k {
+	case AcquisitionRequested:
+		return "requested"
+	case AcquisitionGranted:
+		return "granted"
+	case AcquisitionFailed:
+		return "failed"
+	case AcquisitionReleased:
+		return "released"
+	default:
+		return "unknown"
+	}
+}
+
+// AcquisitionEvent is a step of a lock operation, for reconstructing the order in which
+// locks were requested, granted and released, e.g. after a deadlock.
+type AcquisitionEvent struct {
+	Kind       AcquisitionKind
+	Operation  Operation
+	ResourceID int64
+	Mode       LockMode
+	Time       time.Time
+	// Err is the error of a failed operation; nil when an attempt found the lock taken.
+	Err error
+}
+
+// begin starts operation op, tracing the request of lock operations, and returns its
+// start time for record.
+func (m *Mutex) begin(op Operation) time.Time {
+	start := time.Now()
+	if (m.traceFn != nil || m.events != nil) && !op.isUnlock() {
+		m.trace(AcquisitionRequested, op, start, nil)
+	}
+	return start
+}
+
+// traceEnd traces the end of operation op.
+func (m *Mutex) traceEnd(op Operation, success bool, err error) {
+	kind := AcquisitionFailed
+	if success {
+		kind = AcquisitionGranted
+		if op.isUnlock() {
+			kind = AcquisitionReleased
+		}
+	}
+	m.trace(kind, op, time.Now(), err)
+}
+
+func (m *Mutex) trace(kind AcquisitionKind, op Operation, t time.Time, err error) {
+	mode := ModeExclusive
+	if op == OpLockShared || op == OpTryLockShared || op == OpUnlockShared {
+		mode = ModeShared
+	}
+	e := AcquisitionEvent{Kind: kind, Operation: op, ResourceID: m.so.id, Mode: mode, Time: t, Err: err}
+	if m.traceFn != nil {
+		m.traceFn(e)
+	}
+	if m.events != nil {
+		select {
+		case m.events <- e:
+		default:
+		}
+	}
+}
+
+// Events returns the channel of WithEventChannel, or nil if it is not configured.
+func (m *Mutex) Events() <-chan AcquisitionEvent {
+	return m.events
+}
@@ -0,0 +1,57 @@
+package pgxmutex
+
+import (
+	"sync"
+	"time"
+)
+
+// MutexInfo describes a Mutex holding a lock, as returned by ActiveMutexes.
+type MutexInfo struct {
+	ResourceID int64
+	Exclusive  bool
+	Shared     int
+	HeldSince  time.Time
+	HeldFor    time.Duration
+}
+
+// tracked holds the Mutexes created WithTracking that currently hold a lock.
+var tracked = make(map[*Mutex]struct{})
+var trackedMutex sync.Mutex
+
+// ActiveMutexes lists the Mutexes created WithTracking that hold a lock, to find the
+// culprit when a process holds locks it should not.
+func ActiveMutexes() []MutexInfo {
+	trackedMutex.Lock()
+	ms := make([]*Mutex, 0, len(tracked))
+	for m := range tracked {
+		ms = append(ms, m)
+	}
+	trackedMutex.Unlock()
+
+	infos := make([]MutexInfo, 0, len(ms))
+	for _, m := range ms {
+		m.m.Lock()
+		info := MutexInfo{ResourceID: m.so.id, Exclusive: m.held, Shared: m.shared, HeldSince: m.heldSince}
+		m.m.Unlock()
+		if info.Exclusive || info.Shared > 0 {
+			info.HeldFor = time.Since(info.HeldSince)
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// track registers or deregisters m depending on whether it holds a lock.
+// Must be called with m.m held.
+func (m *Mutex) track() {
+	if !m.tracking {
+		return
+	}
+	trackedMutex.Lock()
+	defer trackedMutex.Unlock()
+	if m.held || m.shared > 0 {
+		tracked[m] = struct{}{}
+	} else {
+		delete(tracked, m)
+	}
+}
@@ -0,0 +1,49 @@
+package pgxmutex
+
+import "time"
+
+// startHoldWatchdog arms the WithMaxHoldDuration timer for the hold started at heldSince.
+// Must be called with m.m held.
+func (m *Mutex) startHoldWatchdog() {
+	if m.maxHold <= 0 || m.holdTimer != nil {
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(m.maxHold-time.Since(m.heldSince), func() { m.maxHoldExceeded(t) })
+	m.holdTimer = t
+}
+
+// stopHoldWatchdog disarms the timer once nothing is held. Must be called with m.m held.
+func (m *Mutex) stopHoldWatchdog() {
+	if m.holdTimer != nil {
+		m.holdTimer.Stop()
+		m.holdTimer = nil
+	}
+}
+
+// maxHoldExceeded reports a hold that outlived the maximum hold duration and releases it
+// if WithAutoReleaseAfterMaxHold is set.
+func (m *Mutex) maxHoldExceeded(t *time.Timer) {
+	m.m.Lock()
+	if m.holdTimer != t {
+		m.m.Unlock()
+		return
+	}
+	m.holdTimer = nil
+	heldFor := time.Since(m.heldSince)
+	held, shared, ctx := m.held, m.shared, m.ctx
+	m.m.Unlock()
+
+	if m.onMaxHold != nil {
+		m.onMaxHold(m.so.id, heldFor)
+	}
+	if !m.autoRelease {
+		return
+	}
+	if held {
+		_, _ = m.unlockExclusive(ctx)
+	}
+	for ; shared > 0; shared-- {
+		_, _ = m.unlockShared(ctx)
+	}
+}
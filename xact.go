@@ -0,0 +1,72 @@
+package pgxmutex
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner is a connection that can begin transactions, such as *pgx.Conn or *pgxpool.Pool.
+type TxBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// BeginLockedTx begins a transaction at the given isolation level and takes the
+// transaction-level advisory lock for id inside it. The lock is held until the
+// transaction ends. The returned end function commits when err is nil and rolls back
+// otherwise; it returns err, or the commit error.
+//
+// A ctx deadline is applied as a transaction-local lock_timeout, which fails the lock
+// statement before the deadline cancels it. Rollback and commit do not use ctx, which
+// may be done by then.
+func BeginLockedTx(ctx context.Context, conn TxBeginner, isoLevel pgx.TxIsoLevel, id int64) (pgx.Tx, func(err error) error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: isoLevel})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Cleanup runs even if ctx is done, so that the transaction and its lock do not leak
+	cleanup := context.WithoutCancel(ctx)
+	budget, ok := LockTimeoutFromContext(ctx)
+	if ok {
+		if budget <= 0 {
+			tx.Rollback(cleanup)
+			return nil, nil, ErrLockTimeout
+		}
+		if _, err := tx.Exec(ctx, "SELECT set_config('lock_timeout', $1, true)", lockTimeoutSetting(budget)); err != nil {
+			tx.Rollback(cleanup)
+			return nil, nil, fmt.Errorf("failed to set lock timeout: %w", err)
+		}
+	}
+
+	lctx := ctx
+	if ok {
+		var cancel context.CancelFunc
+		lctx, cancel = lockTimeoutContext(ctx, budget)
+		defer cancel()
+	}
+	if _, err := tx.Exec(lctx, sqlXactLock, id); err != nil {
+		tx.Rollback(cleanup)
+		if DefaultErrorClassifier(err) == ErrorKindTimeout {
+			return nil, nil, fmt.Errorf("%w: %w", ErrLockTimeout, err)
+		}
+		return nil, nil, fmt.Errorf("failed to acquire transaction lock: %w", err)
+	}
+
+	end := func(err error) error {
+		if err != nil {
+			tx.Rollback(cleanup)
+			return err
+		}
+		if err := tx.Commit(cleanup); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+	return tx, end, nil
+}
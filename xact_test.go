@@ -0,0 +1,129 @@
+package pgxmutex
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// beginnerFunc adapts a function to TxBeginner.
+type beginnerFunc func(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+
+func (f beginnerFunc) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return f(ctx, txOptions)
+}
+
+func TestBeginLockedTxDoneContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	begin := beginnerFunc(func(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+		t.Fatal("transaction begun with a done context")
+		return nil, nil
+	})
+	if _, _, err := BeginLockedTx(ctx, begin, pgx.Serializable, 125); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BeginLockedTx = %v, want context.Canceled", err)
+	}
+}
+
+func TestBeginLockedTx(t *testing.T) {
+	ctx := context.Background()
+	connStr := testConnStr(t)
+	conn, err := pgx.Connect(ctx, connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+	other := newTestMutex(t, WithConnStr(connStr), WithResourceID(125), WithoutLocalSerialization())
+
+	tx, end, err := BeginLockedTx(ctx, conn, pgx.Serializable, 125)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var iso string
+	if err := tx.QueryRow(ctx, "SHOW transaction_isolation").Scan(&iso); err != nil {
+		t.Fatal(err)
+	}
+	if iso != "serializable" {
+		t.Errorf("isolation %q, want serializable", iso)
+	}
+	if ok, err := other.TryLock(); err != nil || ok {
+		t.Fatalf("TryLock of another session inside the transaction = %t, %v, want false", ok, err)
+	}
+
+	if err := end(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := other.TryLock(); err != nil || !ok {
+		t.Fatalf("TryLock after commit = %t, %v, want true", ok, err)
+	}
+}
+
+// strictTx is a fakeTx whose Commit and Rollback fail on a done ctx, like pgx does.
+type strictTx struct {
+	fakeTx
+	ended string
+}
+
+func (tx *strictTx) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx.ended = "commit"
+	return tx.fakeTx.Commit(ctx)
+}
+
+func (tx *strictTx) Rollback(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx.ended = "rollback"
+	return tx.fakeTx.Rollback(ctx)
+}
+
+func TestBeginLockedTxDeadlineRollsBack(t *testing.T) {
+	db := newFakeDB()
+	holdFake(t, db, 125)
+	s := db.session()
+	s.closeOnCancel = true
+	tx := &strictTx{fakeTx: fakeTx{s: s}}
+	begin := beginnerFunc(func(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) { return tx, nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := BeginLockedTx(ctx, begin, pgx.ReadCommitted, 125); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("BeginLockedTx = %v, want ErrLockTimeout", err)
+	}
+	if s.closed {
+		t.Error("session closed by the ctx deadline")
+	}
+	if tx.ended != "rollback" {
+		t.Errorf("transaction ended with %q, want rollback", tx.ended)
+	}
+}
+
+func TestBeginLockedTxEndAfterContextDone(t *testing.T) {
+	for name, endErr := range map[string]error{"commit": nil, "rollback": errors.New("work failed")} {
+		t.Run(name, func(t *testing.T) {
+			db := newFakeDB()
+			tx := &strictTx{fakeTx: fakeTx{s: db.session()}}
+			begin := beginnerFunc(func(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) { return tx, nil })
+
+			ctx, cancel := context.WithCancel(context.Background())
+			_, end, err := BeginLockedTx(ctx, begin, pgx.ReadCommitted, 125)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			if err := end(endErr); err != endErr {
+				t.Fatalf("end = %v, want %v", err, endErr)
+			}
+			if tx.ended != name {
+				t.Errorf("transaction ended with %q, want %s", tx.ended, name)
+			}
+			assertFree(t, db, 125)
+		})
+	}
+}